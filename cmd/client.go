@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yichen/conductor/server"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// requestTimeout bounds the requests of the client commands
+const requestTimeout = 10 * time.Second
+
+// addr is the address of the server that the client commands connect to
+var addr string
+
+// addClientFlags adds the flags of the commands that call a server
+func addClientFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&addr, "addr", "localhost:50000", "address of a conductor server")
+}
+
+// dial connects to the server at --addr. The caller closes the connection.
+func dial() (server.JobServiceClient, *grpc.ClientConn, error) {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return server.NewJobServiceClient(conn), conn, nil
+}
+
+// requestContext returns the context of a request to the server
+func requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), requestTimeout)
+}
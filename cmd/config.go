@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yichen/conductor/server"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// configCmd groups the commands of the server config
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the server config",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective server config",
+	Long: `Print the server config as YAML, as loaded from the config file, the
+environment and the default flags, and check that it is valid.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := readConfig()
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %v", err)
+		}
+
+		return nil
+	},
+}
+
+// readConfig returns the server config from the config file, the
+// environment and the flags, over the defaults
+func readConfig() (server.Config, error) {
+	cfg := server.DefaultConfig()
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return cfg, fmt.Errorf("failed to read config: %v", err)
+	}
+
+	return cfg, nil
+}
+
+// loadConfig returns the server config, and fails if it is not valid
+func loadConfig() (server.Config, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config: %v", err)
+	}
+
+	return cfg, nil
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPrintCmd)
+}
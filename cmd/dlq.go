@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/cobra"
+	"github.com/yichen/conductor/server"
+)
+
+// redriveState is the state that redriven jobs start again in
+var redriveState string
+
+// dlqCmd groups the commands of the dead-letter queues
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Inspect and redrive dead-lettered jobs",
+	Long: `Inspect the dead-letter queue of a workflow, which holds the jobs that
+failed without retry and the log messages that could not be decoded, and
+redrive selected jobs back to a state.`,
+}
+
+var dlqListCmd = &cobra.Command{
+	Use:   "list WORKFLOW",
+	Short: "List the dead-lettered jobs of a workflow with their last error",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		c, conn, err := dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		l, err := c.ListDeadLetters(ctx, &server.DeadLetterRequest{Workflow: args[0]})
+		if err != nil {
+			return err
+		}
+
+		for _, dl := range l.Entries {
+			fmt.Printf("%s\t%s\t%s\t%s\n", dl.Name, dl.State,
+				time.Unix(0, dl.FailedAt).UTC().Format(time.RFC3339), dl.Error)
+		}
+
+		return nil
+	},
+}
+
+var dlqGetCmd = &cobra.Command{
+	Use:   "get WORKFLOW NAME",
+	Short: "Show a dead-lettered job with its payload",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		c, conn, err := dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		dl, err := c.GetDeadLetter(ctx, &server.DeadLetterRequest{Workflow: args[0], Name: args[1]})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("workflow:  %s\n", dl.Workflow)
+		fmt.Printf("name:      %s\n", dl.Name)
+		fmt.Printf("state:     %s\n", dl.State)
+		fmt.Printf("error:     %s\n", dl.Error)
+		fmt.Printf("failed at: %s\n", time.Unix(0, dl.FailedAt).UTC().Format(time.RFC3339))
+		fmt.Printf("message:   [%d]@%d\n", dl.Partition, dl.Offset)
+
+		// the payload of a message that could not be decoded is printed
+		// as is
+		j := server.Job{}
+		if err := proto.Unmarshal(dl.Payload, &j); err != nil {
+			fmt.Printf("payload:   %q\n", dl.Payload)
+		} else {
+			fmt.Printf("payload:\n%s", proto.MarshalTextString(&j))
+		}
+
+		return nil
+	},
+}
+
+var dlqRedriveCmd = &cobra.Command{
+	Use:   "redrive WORKFLOW NAME...",
+	Short: "Publish dead-lettered jobs again",
+	Long: `Publish dead-lettered jobs again with their attempts reset, in the state
+given by --state, or in their last state.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		c, conn, err := dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		for _, name := range args[1:] {
+			ctx, cancel := requestContext()
+			j, err := c.RedriveJob(ctx, &server.RedriveRequest{
+				Workflow: args[0],
+				Name:     name,
+				State:    redriveState,
+			})
+			cancel()
+			if err != nil {
+				return fmt.Errorf("failed to redrive job %s: %v", name, err)
+			}
+
+			fmt.Printf("redrove job %s to state %s\n", j.Name, j.State)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(dlqCmd)
+	dlqCmd.AddCommand(dlqListCmd, dlqGetCmd, dlqRedriveCmd)
+	addClientFlags(dlqCmd)
+
+	dlqRedriveCmd.Flags().StringVar(&redriveState, "state", "", "state to redrive the jobs to (default is their last state)")
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yichen/conductor/server"
+)
+
+var (
+	// cancelState selects the state of the jobs to cancel
+	cancelState string
+	// cancelLabels select the labels of the jobs to cancel, as key=value
+	cancelLabels []string
+	// cancelDryRun counts the jobs to cancel without cancelling them
+	cancelDryRun bool
+)
+
+// jobCmd groups the commands that inspect and cancel jobs
+var jobCmd = &cobra.Command{
+	Use:   "job",
+	Short: "Inspect and cancel jobs",
+}
+
+var jobHistoryCmd = &cobra.Command{
+	Use:   "history WORKFLOW NAME",
+	Short: "Show the events of a job in time order",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		c, conn, err := dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		h, err := c.GetJobHistory(ctx, &server.JobHistoryRequest{Workflow: args[0], Name: args[1]})
+		if err != nil {
+			return err
+		}
+
+		for _, e := range h.Events {
+			fmt.Printf("%s\t%s\t%s\n", time.Unix(0, e.Time).UTC().Format(time.RFC3339Nano), e.Type, eventDetail(e))
+		}
+
+		return nil
+	},
+}
+
+var jobCancelCmd = &cobra.Command{
+	Use:   "cancel WORKFLOW NAME...",
+	Short: "Cancel jobs that are queued, delayed or polled",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		c, conn, err := dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		for _, name := range args[1:] {
+			ctx, cancel := requestContext()
+			_, err := c.CancelJob(ctx, &server.CancelRequest{Workflow: args[0], Name: name})
+			cancel()
+			if err != nil {
+				return fmt.Errorf("failed to cancel job %s: %v", name, err)
+			}
+
+			fmt.Printf("cancelled job %s\n", name)
+		}
+
+		return nil
+	},
+}
+
+var jobCancelAllCmd = &cobra.Command{
+	Use:   "cancel-all WORKFLOW",
+	Short: "Cancel the active jobs of a workflow that match a state and labels",
+	Long: `Cancel the active jobs of a workflow, selected by --state and --label.
+With --dry-run the jobs are counted without being cancelled.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		labels := make(map[string]string)
+		for _, l := range cancelLabels {
+			i := strings.Index(l, "=")
+			if i <= 0 {
+				return fmt.Errorf("invalid label %q, expected key=value", l)
+			}
+			labels[l[:i]] = l[i+1:]
+		}
+
+		c, conn, err := dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		res, err := c.CancelJobs(ctx, &server.BulkCancelRequest{
+			Workflow: args[0],
+			State:    cancelState,
+			Labels:   labels,
+			DryRun:   cancelDryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		if cancelDryRun {
+			fmt.Printf("%d jobs would be cancelled\n", res.Count)
+		} else {
+			fmt.Printf("cancelled %d jobs\n", res.Count)
+		}
+
+		return nil
+	},
+}
+
+// eventDetail describes what happened in an event of a job
+func eventDetail(e *server.JobEvent) string {
+	switch e.Type {
+	case server.JobEvent_POLLED:
+		return fmt.Sprintf("state %s, leased by worker %q", e.State, e.Worker)
+	case server.JobEvent_HEARTBEAT:
+		return fmt.Sprintf("state %s, worker %q, progress %q", e.State, e.Worker, e.Progress)
+	case server.JobEvent_TRANSITIONED:
+		return fmt.Sprintf("from %s to %s", e.PreviousState, e.State)
+	case server.JobEvent_FAILED:
+		return fmt.Sprintf("state %s, error %q", e.State, e.Error)
+	case server.JobEvent_RETRIED:
+		return fmt.Sprintf("state %s, attempt %d, error %q", e.State, e.Attempt, e.Error)
+	default:
+		return fmt.Sprintf("state %s", e.State)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(jobCmd)
+	jobCmd.AddCommand(jobHistoryCmd, jobCancelCmd, jobCancelAllCmd)
+	addClientFlags(jobCmd)
+
+	jobCancelAllCmd.Flags().StringVar(&cancelState, "state", "", "cancel the jobs in this state (default is any state)")
+	jobCancelAllCmd.Flags().StringSliceVar(&cancelLabels, "label", nil, "cancel the jobs with this label, as key=value")
+	jobCancelAllCmd.Flags().BoolVar(&cancelDryRun, "dry-run", false, "count the jobs without cancelling them")
+}
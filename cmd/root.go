@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
@@ -20,6 +21,9 @@ examples and usage of using your application.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+
+	// errors are printed by Execute
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -62,7 +66,11 @@ func initConfig() {
 		viper.SetConfigName(".conductor")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// read in environment variables that match, e.g. CONDUCTOR_DATA_DIR
+	// for data-dir and CONDUCTOR_KAFKA_BROKERS for kafka.brokers
+	viper.SetEnvPrefix("conductor")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
+	viper.AutomaticEnv()
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
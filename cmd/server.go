@@ -2,37 +2,99 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
-)
-
-var (
-	kafka string
-	name  string
+	"github.com/spf13/viper"
+	"github.com/yichen/conductor/server"
 )
 
 // serverCmd represents the server command
 var serverCmd = &cobra.Command{
 	Use:   "server",
-	Short: "A brief description of your command",
-	Long:  `A longer description`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if kafka == "" {
-			fmt.Println("Missing argument --kafka")
-			return
-		}
+	Short: "Run a conductor server",
+	Long: `Run a conductor server that joins the cluster identified by --name,
+using the Kafka brokers given by --kafka. With --transport memory the server
+runs a single node on an in-process log instead, for development. The server
+runs until it receives SIGINT or SIGTERM, and exits with a non-zero code if
+it fails to start or fails while it runs.
 
-		if name == "" {
-			fmt.Println("Missing argument --name")
-			return
+Every flag can also be set in the config file, or in the environment with
+the CONDUCTOR_ prefix, e.g. CONDUCTOR_DATA_DIR for --data-dir. Run
+"conductor config print" to see the effective config.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
 		}
 
+		// the config is valid, so don't print the usage on failures
+		cmd.SilenceUsage = true
+
+		return runServer(cfg)
 	},
 }
 
+// runServer starts a server and blocks until SIGINT or SIGTERM is received,
+// or the server fails
+func runServer(cfg server.Config) error {
+	s, err := server.NewServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	sigchan := make(chan os.Signal, 1)
+	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigchan)
+
+	if err := s.Start(); err != nil {
+		s.Stop()
+		return err
+	}
+
+	select {
+	case sig := <-sigchan:
+		fmt.Printf("Caught signal %v: terminating\n", sig)
+		s.Stop()
+		return nil
+
+	case err := <-s.Err():
+		s.Stop()
+		return fmt.Errorf("server failed: %v", err)
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(serverCmd)
 
-	serverCmd.Flags().StringVarP(&kafka, "kafka", "k", "", "kafka broker list")
-	serverCmd.Flags().StringVarP(&name, "name", "n", "", "conductor cluster name")
+	d := server.DefaultConfig()
+	flags := serverCmd.Flags()
+	flags.StringP("kafka", "k", "", "kafka broker list")
+	flags.StringP("name", "n", "", "conductor cluster name")
+	flags.String("transport", d.Transport, "log transport: kafka or memory")
+	flags.String("listen-address", d.ListenAddress, "host:port the API listens on")
+	flags.String("advertise-address", "", "host:port of the API for the other members (default is the host name and the listen port)")
+	flags.String("data-dir", "", "directory of the local database, not with --transport memory (default is a temporary directory)")
+	flags.Int("queue-size", d.QueueSize, "capacity of each workflow state queue")
+	flags.Duration("lease-timeout", d.LeaseTimeout, "visibility timeout of polled jobs")
+	flags.StringSlice("kafka-setting", nil, "key=value passed through to the kafka clients")
+	flags.String("log-level", d.Log.Level, "log level: info or debug")
+
+	// the flags are bound to the keys of the config file
+	for key, flag := range map[string]string{
+		"kafka.brokers":     "kafka",
+		"name":              "name",
+		"transport":         "transport",
+		"listen-address":    "listen-address",
+		"advertise-address": "advertise-address",
+		"data-dir":          "data-dir",
+		"queue-size":        "queue-size",
+		"lease-timeout":     "lease-timeout",
+		"kafka.settings":    "kafka-setting",
+		"log.level":         "log-level",
+	} {
+		viper.BindPFlag(key, flags.Lookup(flag))
+	}
 }
@@ -1,56 +1,941 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// ErrJobCancelled is returned for the lease of a job that was cancelled
+var ErrJobCancelled = errors.New("job cancelled")
+
 const (
-	port = ":50000"
+	// maxPollWait caps the long-poll wait of a poll request
+	maxPollWait = 30 * time.Second
+	// peerPollInterval is how often the other members are polled during a
+	// long-poll
+	peerPollInterval = time.Second
+	// maxBatchSize is the number of jobs of a batch request at most
+	maxBatchSize = 100
 )
 
 // API is the API server
 type API struct {
 	sync.WaitGroup
-	server *grpc.Server
+	// address is the host:port the API listens on
+	address   string
+	server    *grpc.Server
+	producer  *Producer
+	memstore  *MemStore
+	workflows *Workflows
+	router    *Router
 }
 
-// NewAPI creates a new API server instance
-func NewAPI() *API {
+// NewAPI creates a new API server instance listening on address. Requests
+// for jobs owned by other members are forwarded by the router.
+func NewAPI(address string, producer *Producer, memstore *MemStore, workflows *Workflows, router *Router) *API {
 	return &API{
-		server: grpc.NewServer(),
+		address:   address,
+		server:    grpc.NewServer(),
+		producer:  producer,
+		memstore:  memstore,
+		workflows: workflows,
+		router:    router,
 	}
 }
 
-// AddJob add a new job to the workflow
+// AddJob add a new job to the workflow. The job is published to the log
+// and the call returns once the log has durably accepted it, with the
+// partition and offset assigned to the job. The request is forwarded to the
+// owner of the job, which rejects the job when the queue of its state is
+// full, or when a job of the same name is not finished. A job that is
+// waiting in the same state has its priority raised instead, when the new
+// priority is higher. The job is added as active, whatever the status and
+// the other server fields sent by the client.
 func (s *API) AddJob(ctx context.Context, j *Job) (*Job, error) {
+	if err := validateJob(j); err != nil {
+		return nil, err
+	}
+
+	wf, err := s.workflow(j.Workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	if wf.State(j.State) == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown state %s in workflow %s", j.State, j.Workflow)
+	}
+
+	c, err := s.route(ctx, j.Workflow, j.Name)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.AddJob(forwarded(ctx), j)
+	}
+
+	p, err := s.router.Partition(j.Workflow, j.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if w, ok := s.memstore.Waiting(j.Workflow, j.Name); ok && w.State == j.State && j.Priority > w.Priority {
+		return s.raise(w, j.Priority)
+	}
+
+	if err := s.admit(p, j); err != nil {
+		return nil, err
+	}
+
+	// only the fields set by the client are kept, the status, the attempt,
+	// the lease and the position in the log belong to the server
+	j = &Job{
+		Workflow:    j.Workflow,
+		Name:        j.Name,
+		State:       j.State,
+		Data:        j.Data,
+		Priority:    j.Priority,
+		NotBefore:   j.NotBefore,
+		Labels:      j.Labels,
+		Status:      Job_ACTIVE,
+		SubmittedAt: time.Now().UnixNano(),
+	}
+
+	if err := s.producer.Produce(j); err != nil {
+		s.memstore.Unreserve(*j)
+		return nil, status.Errorf(codes.Unavailable, "failed to publish job: %v", err)
+	}
+
 	return j, nil
 }
 
-// Start starts the API server
-func (s *API) Start() {
-	fmt.Println("starting API...")
-	s.Add(1)
-	go s.runServer()
+// raise publishes a priority update of a waiting job. The update is
+// ignored if the job moves on before the update is applied.
+func (s *API) raise(j Job, priority int32) (*Job, error) {
+	u := &Job{
+		Workflow:       j.Workflow,
+		Name:           j.Name,
+		State:          j.State,
+		Priority:       priority,
+		PriorityUpdate: true,
+	}
+
+	if err := s.producer.Produce(u); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to publish job: %v", err)
+	}
+
+	j.Priority = priority
+	j.Partition = u.Partition
+	j.Offset = u.Offset
+	j.LeaseToken = ""
+
+	return &j, nil
 }
 
-func (s *API) runServer() {
+// PollJob takes a job that is ready in a workflow state. The job stays
+// hidden from other workers until it is completed or failed. When this
+// member has no job ready, the other members are polled in turn. With a
+// wait, the poll waits for a job to be offered until the wait or the
+// request deadline is over.
+func (s *API) PollJob(ctx context.Context, r *PollRequest) (*Job, error) {
+	if r.GetWorkflow() == "" || r.GetState() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or state")
+	}
 
-	defer s.Done()
+	jobs := s.poll(ctx, r.Workflow, r.State, r.Worker, r.WaitMs, 1, func(c JobServiceClient, n int) []*Job {
+		j, err := c.PollJob(forwarded(ctx), &PollRequest{Workflow: r.Workflow, State: r.State, Worker: r.Worker})
+		if err != nil {
+			return nil
+		}
+		return []*Job{j}
+	})
+	if len(jobs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no job in %s:%s", r.Workflow, r.State)
+	}
+
+	return jobs[0], nil
+}
+
+// PollJobs takes up to a number of jobs that are ready in a workflow state,
+// each with its own lease. It waits like PollJob until a job is ready, and
+// returns the jobs ready at that time, which may be none.
+func (s *API) PollJobs(ctx context.Context, r *BatchPollRequest) (*JobList, error) {
+	if r.GetWorkflow() == "" || r.GetState() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or state")
+	}
+	if r.GetMax() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "max must be at least 1")
+	}
+
+	max := int(r.Max)
+	if max > maxBatchSize {
+		max = maxBatchSize
+	}
+
+	jobs := s.poll(ctx, r.Workflow, r.State, r.Worker, r.WaitMs, max, func(c JobServiceClient, n int) []*Job {
+		l, err := c.PollJobs(forwarded(ctx), &BatchPollRequest{Workflow: r.Workflow, State: r.State, Max: int32(n), Worker: r.Worker})
+		if err != nil {
+			return nil
+		}
+		return l.Jobs
+	})
+
+	return &JobList{Jobs: jobs}, nil
+}
+
+// poll takes up to max jobs from this member, then from the other members
+// with fromPeer, until there is a job or the wait is over. The jobs of this
+// member are recorded as polled by the worker in their history.
+func (s *API) poll(ctx context.Context, workflow string, state string, worker string, waitMs int32, max int, fromPeer func(c JobServiceClient, n int) []*Job) []*Job {
+	wait := time.Duration(waitMs) * time.Millisecond
+	if wait > maxPollWait {
+		wait = maxPollWait
+	}
+	wctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	// peers are polled without waiting, so that a job offered to any
+	// member is taken by one waiting poll only
+	peers := s.router.Peers(ctx)
+
+	interval := time.Duration(0)
+	for {
+		lctx, lcancel := context.WithTimeout(wctx, interval)
+		jobs := s.memstore.PollBatch(lctx, workflow, state, max)
+		lcancel()
+
+		for _, j := range jobs {
+			e := jobEvent(*j, time.Now())
+			e.Type = JobEvent_POLLED
+			e.Worker = worker
+			s.record(*j, e)
+		}
+
+		for _, c := range peers {
+			if len(jobs) == max {
+				break
+			}
+			jobs = append(jobs, fromPeer(c, max-len(jobs))...)
+		}
+
+		if len(jobs) > 0 || wctx.Err() != nil {
+			return jobs
+		}
+
+		interval = peerPollInterval
+		if len(peers) == 0 {
+			interval = wait
+		}
+	}
+}
+
+// CompleteJob completes a polled job. When a next state is given the job
+// moves on to that state, unless the queue of that state is full, otherwise
+// the job is done.
+func (s *API) CompleteJob(ctx context.Context, r *CompleteRequest) (*Job, error) {
+	c, err := s.route(ctx, r.GetWorkflow(), r.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.CompleteJob(forwarded(ctx), r)
+	}
+
+	j, err := s.leased(r.GetWorkflow(), r.GetName(), r.GetLeaseToken())
+	if err != nil {
+		return nil, err
+	}
+	state := j.State
+
+	wf, err := s.workflow(j.Workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.NextState != "" {
+		if !wf.CanTransition(state, r.NextState) {
+			return nil, status.Errorf(codes.FailedPrecondition, "illegal transition from %s to %s in workflow %s", state, r.NextState, j.Workflow)
+		}
+		j.State = r.NextState
+		// the attempts are counted per state
+		j.Attempt = 0
+	} else {
+		if st := wf.State(state); st == nil || !st.Terminal {
+			return nil, status.Errorf(codes.FailedPrecondition, "state %s of workflow %s is not terminal", state, j.Workflow)
+		}
+		j.Status = Job_COMPLETED
+	}
+
+	if r.Data != "" {
+		j.Data = r.Data
+	}
+
+	return s.publish(j, state)
+}
+
+// FailJob fails a polled job with an error message. The retry policy of the
+// state retries the job after a backoff, or moves it to the failure state.
+// Without a policy the job is done.
+func (s *API) FailJob(ctx context.Context, r *FailRequest) (*Job, error) {
+	c, err := s.route(ctx, r.GetWorkflow(), r.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.FailJob(forwarded(ctx), r)
+	}
+
+	j, err := s.leased(r.GetWorkflow(), r.GetName(), r.GetLeaseToken())
+	if err != nil {
+		return nil, err
+	}
+
+	state := j.State
+	j.Error = r.Error
+	failJob(s.workflows.Get(j.Workflow), j, r.Code, time.Now())
+
+	return s.publish(j, state)
+}
+
+// CompleteJobs completes polled jobs. The jobs are completed one by one,
+// and the result of each job is returned in order.
+func (s *API) CompleteJobs(ctx context.Context, r *BatchCompleteRequest) (*BatchResult, error) {
+	if len(r.GetRequests()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "more than %d requests in a batch", maxBatchSize)
+	}
+
+	results := make([]*JobResult, len(r.Requests))
+	for i, req := range r.Requests {
+		results[i] = jobResult(s.CompleteJob(ctx, req))
+	}
+
+	return &BatchResult{Results: results}, nil
+}
+
+// FailJobs fails polled jobs. The jobs are failed one by one, and the result
+// of each job is returned in order.
+func (s *API) FailJobs(ctx context.Context, r *BatchFailRequest) (*BatchResult, error) {
+	if len(r.GetRequests()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "more than %d requests in a batch", maxBatchSize)
+	}
+
+	results := make([]*JobResult, len(r.Requests))
+	for i, req := range r.Requests {
+		results[i] = jobResult(s.FailJob(ctx, req))
+	}
+
+	return &BatchResult{Results: results}, nil
+}
+
+// jobResult returns the result of a request of a batch
+func jobResult(j *Job, err error) *JobResult {
+	if err != nil {
+		st, ok := status.FromError(err)
+		if !ok || st == nil {
+			return &JobResult{Code: int32(codes.Unknown), Error: err.Error()}
+		}
+		return &JobResult{Code: int32(st.Code()), Error: st.Message()}
+	}
+
+	return &JobResult{Job: j}
+}
+
+// ExtendLease renews the lease of a polled job, so that a long running
+// job is not redelivered to another worker. The heartbeat is rejected if
+// the lease has expired or the job was polled by another worker.
+func (s *API) ExtendLease(ctx context.Context, r *LeaseRequest) (*Job, error) {
+	if r.GetWorkflow() == "" || r.GetName() == "" || r.GetLeaseToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow, job name or lease token")
+	}
+
+	c, err := s.route(ctx, r.Workflow, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.ExtendLease(forwarded(ctx), r)
+	}
+
+	j, err := s.memstore.Extend(r.Workflow, r.Name, r.LeaseToken, r.Progress)
+	if err == ErrLeaseExpired {
+		return nil, s.expiredLease(r.Workflow, r.Name)
+	}
+	if err != nil {
+		return nil, leaseError(jobKey(r.Workflow, r.Name), err)
+	}
+
+	e := jobEvent(j, time.Now())
+	e.Type = JobEvent_HEARTBEAT
+	e.Worker = r.Worker
+	s.record(j, e)
+
+	return &j, nil
+}
+
+// GetJobHistory returns the events of a job in time order, from the member
+// that owns the job
+func (s *API) GetJobHistory(ctx context.Context, r *JobHistoryRequest) (*JobHistory, error) {
+	if r.GetWorkflow() == "" || r.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or job name")
+	}
+
+	c, err := s.route(ctx, r.Workflow, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.GetJobHistory(forwarded(ctx), r)
+	}
+
+	events, err := s.memstore.history.Get(r.Workflow, r.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read history of job %s: %v", jobKey(r.Workflow, r.Name), err)
+	}
+	if len(events) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no history of job %s", jobKey(r.Workflow, r.Name))
+	}
+
+	return &JobHistory{Events: events}, nil
+}
+
+// GetJob returns the current state of a job, from the member that owns the
+// job
+func (s *API) GetJob(ctx context.Context, r *GetJobRequest) (*JobInfo, error) {
+	if r.GetWorkflow() == "" || r.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or job name")
+	}
+
+	c, err := s.route(ctx, r.Workflow, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.GetJob(forwarded(ctx), r)
+	}
+
+	// a polled job has the progress of its heartbeats
+	if j, expires, ok := s.memstore.Lease(r.Workflow, r.Name); ok {
+		j.LeaseToken = ""
+		return &JobInfo{Job: &j, Leased: true, LeaseExpiresAt: expires.UnixNano()}, nil
+	}
+
+	j, err := s.memstore.records.Get(r.Workflow, r.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read job %s: %v", jobKey(r.Workflow, r.Name), err)
+	}
+	if j == nil {
+		return nil, status.Errorf(codes.NotFound, "job %s not found", jobKey(r.Workflow, r.Name))
+	}
+
+	return &JobInfo{Job: j}, nil
+}
+
+// ListJobs returns a page of the jobs of a workflow in submit time order,
+// filtered by state, labels and submit time. Every member lists the jobs of
+// its partitions from the point of the cursor, and the pages are merged.
+func (s *API) ListJobs(ctx context.Context, r *ListJobsRequest) (*JobPage, error) {
+	if r.GetWorkflow() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow")
+	}
+	if r.GetSubmittedAfter() < 0 || r.GetSubmittedBefore() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "negative submit time")
+	}
+
+	jobs, err := s.memstore.records.List(r, s.memstore.Owns)
+	if err == ErrInvalidCursor {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list jobs: %v", err)
+	}
+
+	for _, c := range s.router.Peers(ctx) {
+		p, err := c.ListJobs(forwarded(ctx), r)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to list jobs of a member: %v", err)
+		}
+		jobs = append(jobs, p.Jobs...)
+	}
+	sortJobs(jobs)
+
+	size := pageSize(r.PageSize)
+	if len(jobs) > size {
+		jobs = jobs[:size]
+	}
+
+	return &JobPage{Jobs: jobs, NextCursor: nextCursor(jobs, size)}, nil
+}
+
+// CancelJob cancels a job that is queued, delayed or polled. The
+// cancellation is written to the log, and the worker of a polled job is
+// told on its next heartbeat.
+func (s *API) CancelJob(ctx context.Context, r *CancelRequest) (*Job, error) {
+	if r.GetWorkflow() == "" || r.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or job name")
+	}
 
-	lis, err := net.Listen("tcp", port)
+	c, err := s.route(ctx, r.Workflow, r.Name)
 	if err != nil {
-		log.Fatalf("failed to lisen: %v", err)
+		return nil, err
+	}
+	if c != nil {
+		return c.CancelJob(forwarded(ctx), r)
+	}
+
+	j, err := s.memstore.records.Get(r.Workflow, r.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read job %s: %v", jobKey(r.Workflow, r.Name), err)
+	}
+	if j == nil {
+		return nil, status.Errorf(codes.NotFound, "job %s not found", jobKey(r.Workflow, r.Name))
+	}
+	if j.Status != Job_ACTIVE {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s is %s", jobKey(r.Workflow, r.Name), j.Status)
+	}
+
+	return s.cancel(j)
+}
+
+// CancelJobs cancels the active jobs of a workflow that match a state and
+// labels, or counts them in a dry run. Every member cancels the jobs of its
+// partitions.
+func (s *API) CancelJobs(ctx context.Context, r *BulkCancelRequest) (*BulkCancelResult, error) {
+	if r.GetWorkflow() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow")
+	}
+
+	req := &ListJobsRequest{Workflow: r.Workflow, State: r.State, Labels: r.Labels, PageSize: maxPageSize}
+	var count int32
+	for {
+		jobs, err := s.memstore.records.List(req, s.memstore.Owns)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list jobs: %v", err)
+		}
+
+		for _, j := range jobs {
+			if j.Status != Job_ACTIVE {
+				continue
+			}
+			if !r.DryRun {
+				if _, err := s.cancel(j); err != nil {
+					return nil, err
+				}
+			}
+			count++
+		}
+
+		if req.Cursor = nextCursor(jobs, maxPageSize); req.Cursor == "" {
+			break
+		}
+	}
+
+	for _, c := range s.router.Peers(ctx) {
+		res, err := c.CancelJobs(forwarded(ctx), r)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to cancel jobs of a member: %v", err)
+		}
+		count += res.Count
+	}
+
+	return &BulkCancelResult{Count: count}, nil
+}
+
+// cancel writes the cancellation of a job to the log. A polled job is
+// released once the cancellation is applied, so that the lease of the
+// worker is found cancelled rather than expired.
+func (s *API) cancel(j *Job) (*Job, error) {
+	j.Status = Job_CANCELLED
+	j.LeaseToken = ""
+	j.PreviousState = ""
+
+	if err := s.producer.Produce(j); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to publish job: %v", err)
+	}
+
+	return j, nil
+}
+
+// record adds an event to the history of a job. A job is served even when
+// its history cannot be written.
+func (s *API) record(j Job, e *JobEvent) {
+	if err := s.memstore.history.Record(j, e); err != nil {
+		fmt.Printf("Failed to record history of job %s. Error: %s\n", jobKey(j.Workflow, j.Name), err.Error())
+	}
+}
+
+// PutWorkflow creates or replaces a workflow definition. The definition is
+// published to the log, which delivers it to every member, and is put here
+// right away so that it can be used as soon as the call returns.
+func (s *API) PutWorkflow(ctx context.Context, d *WorkflowDefinition) (*WorkflowDefinition, error) {
+	wf, err := ParseWorkflow([]byte(d.GetDefinition()))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid workflow definition: %v", err)
+	}
+
+	def := &WorkflowDefinition{
+		Name:       wf.Name,
+		Definition: d.Definition,
+	}
+	if err := s.producer.PublishWorkflow(def); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to publish workflow: %v", err)
+	}
+
+	if _, err := s.workflows.Put([]byte(def.Definition)); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to put workflow: %v", err)
+	}
+
+	return def, nil
+}
+
+// GetWorkflow returns a workflow definition by name
+func (s *API) GetWorkflow(ctx context.Context, d *WorkflowDefinition) (*WorkflowDefinition, error) {
+	if _, err := s.workflow(d.GetName()); err != nil {
+		return nil, err
+	}
+
+	data, err := s.workflows.store.Workflow(d.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read workflow: %v", err)
+	}
+
+	return &WorkflowDefinition{
+		Name:       d.Name,
+		Definition: string(data),
+	}, nil
+}
+
+// ListDeadLetters returns the dead-lettered jobs of a workflow on all
+// members, without their payloads. Each member lists the jobs of the
+// partitions it owns, since the entries of the partitions it owned before
+// a rebalance are stale.
+func (s *API) ListDeadLetters(ctx context.Context, r *DeadLetterRequest) (*DeadLetterList, error) {
+	if r.GetWorkflow() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow")
+	}
+
+	all, err := s.memstore.deadLetters.List(r.Workflow)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read dead-letter queue: %v", err)
+	}
+
+	var entries []*DeadLetter
+	for _, dl := range all {
+		if s.memstore.Owns(dl.Partition) {
+			dl.Payload = nil
+			entries = append(entries, dl)
+		}
+	}
+
+	for _, c := range s.router.Peers(ctx) {
+		l, err := c.ListDeadLetters(forwarded(ctx), r)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to list dead-letter queue of a member: %v", err)
+		}
+		entries = append(entries, l.Entries...)
+	}
+	sortDeadLetters(entries)
+
+	return &DeadLetterList{Entries: entries}, nil
+}
+
+// GetDeadLetter returns a dead-lettered job with its payload
+func (s *API) GetDeadLetter(ctx context.Context, r *DeadLetterRequest) (*DeadLetter, error) {
+	if r.GetWorkflow() == "" || r.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or job name")
+	}
+
+	c, err := s.route(ctx, r.Workflow, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.GetDeadLetter(forwarded(ctx), r)
+	}
+
+	return s.deadLetter(r.Workflow, r.Name)
+}
+
+// RedriveJob publishes a dead-lettered job again, in the chosen state or in
+// its last state, with its attempts reset. Like an added job, it is rejected
+// when the queue of its state is full. The job leaves the dead-letter queue
+// once it is active again.
+func (s *API) RedriveJob(ctx context.Context, r *RedriveRequest) (*Job, error) {
+	if r.GetWorkflow() == "" || r.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or job name")
+	}
+
+	c, err := s.route(ctx, r.Workflow, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c.RedriveJob(forwarded(ctx), r)
+	}
+
+	dl, err := s.deadLetter(r.Workflow, r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Job{}
+	if err := proto.Unmarshal(dl.Payload, j); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s cannot be decoded: %v", jobKey(r.Workflow, r.Name), err)
+	}
+
+	wf, err := s.workflow(j.Workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	j.PreviousState = ""
+	if r.State != "" && r.State != j.State {
+		j.PreviousState = j.State
+		j.State = r.State
+	}
+	if wf.State(j.State) == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown state %s in workflow %s", j.State, j.Workflow)
+	}
+
+	j.Status = Job_ACTIVE
+	j.Error = ""
+	j.Attempt = 0
+	j.NotBefore = 0
+	j.LeaseToken = ""
+
+	p, err := s.router.Partition(j.Workflow, j.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.admit(p, j); err != nil {
+		return nil, err
+	}
+
+	if err := s.producer.Produce(j); err != nil {
+		s.memstore.Unreserve(*j)
+		return nil, status.Errorf(codes.Unavailable, "failed to publish job: %v", err)
+	}
+
+	return j, nil
+}
+
+// deadLetter returns a dead-lettered job of this member
+func (s *API) deadLetter(workflow string, name string) (*DeadLetter, error) {
+	dl, err := s.memstore.deadLetters.Get(workflow, name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read dead-letter queue: %v", err)
+	}
+
+	if dl == nil {
+		return nil, status.Errorf(codes.NotFound, "job %s is not dead-lettered", jobKey(workflow, name))
+	}
+
+	return dl, nil
+}
+
+// route returns a client of the member that owns a job, or nil when the
+// job is served by this member. A job is only served by the owner of its
+// partition, once its consumer caught up with the log, since the state of
+// the job is not known anywhere else. Until then the job is unavailable.
+func (s *API) route(ctx context.Context, workflow string, name string) (JobServiceClient, error) {
+	c, err := s.router.Route(ctx, workflow, name)
+	if err != nil || c != nil {
+		return c, err
+	}
+
+	p, err := s.router.Partition(workflow, name)
+	if err != nil {
+		return nil, err
+	}
+	if !s.memstore.Owns(p) {
+		return nil, status.Errorf(codes.Unavailable, "partition %d of job %s is not owned by this member", p, jobKey(workflow, name))
+	}
+	if !s.memstore.Ready(p) {
+		return nil, status.Errorf(codes.Unavailable, "partition %d of job %s is catching up with the log", p, jobKey(workflow, name))
+	}
+
+	return nil, nil
+}
+
+// admit reserves room for a job in the queue of its state, until the job
+// is applied from the log
+func (s *API) admit(partition int32, j *Job) error {
+	switch err := s.memstore.Admit(partition, *j); err {
+	case nil:
+		return nil
+	case ErrJobActive:
+		// a job moves on by completing it, which checks the transition
+		return status.Errorf(codes.AlreadyExists, "job %s is already active", jobKey(j.Workflow, j.Name))
+	case ErrQueueFull:
+		return status.Errorf(codes.ResourceExhausted, "job %s: %v in %s:%s", jobKey(j.Workflow, j.Name), err, j.Workflow, j.State)
+	default:
+		return status.Errorf(codes.Internal, "failed to admit job %s: %v", jobKey(j.Workflow, j.Name), err)
+	}
+}
+
+// move reserves room for a job in the queue of the state it moves to
+func (s *API) move(j *Job) error {
+	p, err := s.router.Partition(j.Workflow, j.Name)
+	if err != nil {
+		return err
+	}
+
+	switch err := s.memstore.Move(p, *j); err {
+	case nil:
+		return nil
+	case ErrJobActive:
+		// the job is being completed or failed by another request
+		return status.Errorf(codes.Aborted, "job %s is already moving", jobKey(j.Workflow, j.Name))
+	case ErrQueueFull:
+		return status.Errorf(codes.ResourceExhausted, "job %s: %v in %s:%s", jobKey(j.Workflow, j.Name), err, j.Workflow, j.State)
+	default:
+		return status.Errorf(codes.Internal, "failed to move job %s: %v", jobKey(j.Workflow, j.Name), err)
+	}
+}
+
+// workflow returns the definition of a workflow
+func (s *API) workflow(name string) (*Workflow, error) {
+	wf := s.workflows.Get(name)
+	if wf == nil {
+		return nil, status.Errorf(codes.NotFound, "workflow %s is not defined", name)
+	}
+
+	return wf, nil
+}
+
+// leased finds a job that is polled and not yet completed or failed, with
+// the lease token of the current poll. A job polled again after its lease
+// expired cannot be finished without the token of its last poll.
+func (s *API) leased(workflow string, name string, token string) (*Job, error) {
+	if workflow == "" || name == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing workflow or job name")
+	}
+
+	if token == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s: missing lease token", jobKey(workflow, name))
+	}
+
+	// a job whose lease expired while its queue was full is hidden
+	// without a lease until it is redelivered
+	j, ok := s.memstore.Leased(workflow, name)
+	if !ok || j.LeaseToken == "" {
+		return nil, s.expiredLease(workflow, name)
+	}
+
+	if j.LeaseToken != token {
+		return nil, leaseError(jobKey(workflow, name), ErrLeaseReassigned)
+	}
+
+	return &j, nil
+}
+
+// expiredLease returns the error of a job that is no longer polled, which
+// tells the worker whether the job was cancelled
+func (s *API) expiredLease(workflow string, name string) error {
+	if j, err := s.memstore.records.Get(workflow, name); err == nil && j != nil && j.Status == Job_CANCELLED {
+		return leaseError(jobKey(workflow, name), ErrJobCancelled)
+	}
+
+	return leaseError(jobKey(workflow, name), ErrLeaseExpired)
+}
+
+// leaseError maps a lease error to a gRPC status. The worker of a job that
+// is polled again or cancelled gets Aborted, to stop working on the job.
+func leaseError(key string, err error) error {
+	if err == ErrLeaseReassigned || err == ErrJobCancelled {
+		return status.Errorf(codes.Aborted, "job %s: %v", key, err)
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "job %s: %v", key, err)
+}
+
+// publish writes the new job event to the log and releases the job from
+// the state it was polled from. The event carries the lease token it ends,
+// so that a job that moves back to the same state is queued again when the
+// event is applied before the release.
+func (s *API) publish(j *Job, state string) (*Job, error) {
+	j.PreviousState = ""
+	if j.State != state {
+		j.PreviousState = state
+	}
+
+	// a job that moves to another state is held to the capacity of that
+	// state like a new job
+	moved := j.Status == Job_ACTIVE && j.State != state
+	if moved {
+		if err := s.move(j); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.producer.Produce(j); err != nil {
+		if moved {
+			s.memstore.Unreserve(*j)
+		}
+		return nil, status.Errorf(codes.Unavailable, "failed to publish job: %v", err)
+	}
+
+	s.memstore.Release(j.Workflow, state, j.Name, j.LeaseToken)
+	j.LeaseToken = ""
+
+	return j, nil
+}
+
+// validateJob checks the fields required to route a job
+func validateJob(j *Job) error {
+	if j.GetWorkflow() == "" {
+		return status.Error(codes.InvalidArgument, "missing workflow")
+	}
+
+	if j.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "missing job name")
+	}
+
+	if j.GetState() == "" {
+		return status.Error(codes.InvalidArgument, "missing job state")
+	}
+
+	for k, v := range j.GetLabels() {
+		if k == "" || strings.ContainsRune(k, 0) || strings.ContainsRune(v, 0) {
+			return status.Errorf(codes.InvalidArgument, "invalid label %q", k)
+		}
+	}
+
+	return nil
+}
+
+// Start starts the API server. It returns an error if the listen address
+// cannot be listened on.
+func (s *API) Start() error {
+	fmt.Println("starting API...")
+
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
 	}
 	RegisterJobServiceServer(s.server, s)
 
+	s.Add(1)
+	go s.runServer(lis)
+
+	return nil
+}
+
+func (s *API) runServer(lis net.Listener) {
+
+	defer s.Done()
+
 	if err := s.server.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+		log.Printf("failed to serve: %v", err)
 	}
 }
 
@@ -59,5 +944,6 @@ func (s *API) Stop() {
 	fmt.Println("Stopping API...")
 	s.server.GracefulStop()
 	s.Wait()
+	s.router.Close()
 	fmt.Println("API stopped.")
 }
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultListenAddress is the address the API listens on by default
+	defaultListenAddress = ":50000"
+	// defaultQueueSize is the capacity of each workflow/state queue
+	defaultQueueSize = 1024
+	// defaultLeaseTimeout is the default visibility timeout of polled jobs
+	defaultLeaseTimeout = 30 * time.Second
+)
+
+// log levels
+const (
+	// LogInfo prints the events of the services
+	LogInfo = "info"
+	// LogDebug also prints every message of the log
+	LogDebug = "debug"
+)
+
+// reservedKafkaSettings are set by the Kafka transport itself, and cannot
+// be passed through
+var reservedKafkaSettings = []string{
+	"bootstrap.servers",
+	"group.id",
+	"enable.auto.commit",
+	"go.events.channel.enable",
+	"go.application.rebalance.enable",
+	"partition.assignment.strategy",
+}
+
+// Config is the configuration of a server. The keys are the same in the
+// config file, the environment (with a CONDUCTOR_ prefix) and the flags.
+type Config struct {
+	// Name is the name of the cluster, which names the Kafka topics and
+	// the consumer group
+	Name string `mapstructure:"name" yaml:"name"`
+	// Transport selects the log: "kafka", or "memory" for an in-process
+	// log for tests and single node development, shared by the servers of
+	// the same name in the process
+	Transport string `mapstructure:"transport" yaml:"transport"`
+	// ListenAddress is the host:port the API listens on
+	ListenAddress string `mapstructure:"listen-address" yaml:"listen-address"`
+	// AdvertiseAddress is the address other members forward requests to,
+	// by default the host name and the port of the listen address
+	AdvertiseAddress string `mapstructure:"advertise-address" yaml:"advertise-address"`
+	// DataDir is the directory of the local database, a temporary
+	// directory when empty. It is not supported by the memory transport.
+	DataDir string `mapstructure:"data-dir" yaml:"data-dir"`
+	// QueueSize is the capacity of each workflow/state queue
+	QueueSize int `mapstructure:"queue-size" yaml:"queue-size"`
+	// LeaseTimeout is the visibility timeout of polled jobs, for the
+	// states that do not set their own
+	LeaseTimeout time.Duration `mapstructure:"lease-timeout" yaml:"-"`
+	Kafka        KafkaConfig   `mapstructure:"kafka" yaml:"kafka"`
+	Log          LogConfig     `mapstructure:"log" yaml:"log"`
+}
+
+// KafkaConfig is the configuration of the Kafka transport
+type KafkaConfig struct {
+	// Brokers is the comma separated list of the Kafka brokers
+	Brokers string `mapstructure:"brokers" yaml:"brokers"`
+	// Settings are passed through to the Kafka clients as key=value, for
+	// example security.protocol=ssl
+	Settings []string `mapstructure:"settings" yaml:"settings"`
+}
+
+// LogConfig is the configuration of the server output
+type LogConfig struct {
+	// Level is "info", or "debug" to print every message of the log
+	Level string `mapstructure:"level" yaml:"level"`
+}
+
+// DefaultConfig returns the configuration of a server that is not set by
+// the file, the environment or the flags
+func DefaultConfig() Config {
+	return Config{
+		Transport:     "kafka",
+		ListenAddress: defaultListenAddress,
+		QueueSize:     defaultQueueSize,
+		LeaseTimeout:  defaultLeaseTimeout,
+		Log:           LogConfig{Level: LogInfo},
+	}
+}
+
+// Validate checks the configuration of a server
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+
+	switch c.Transport {
+	case "kafka":
+		if c.Kafka.Brokers == "" {
+			return fmt.Errorf("missing kafka brokers")
+		}
+	case "memory":
+		// the in-memory log starts again at offset 0 in every process, so
+		// the WAL offsets of a data directory kept across restarts would
+		// skip the new messages
+		if c.DataDir != "" {
+			return fmt.Errorf("the memory transport does not keep a data dir")
+		}
+	default:
+		return fmt.Errorf("unknown transport %s", c.Transport)
+	}
+
+	if _, _, err := net.SplitHostPort(c.ListenAddress); err != nil {
+		return fmt.Errorf("invalid listen address %s: %v", c.ListenAddress, err)
+	}
+
+	if c.AdvertiseAddress != "" {
+		if _, _, err := net.SplitHostPort(c.AdvertiseAddress); err != nil {
+			return fmt.Errorf("invalid advertise address %s: %v", c.AdvertiseAddress, err)
+		}
+	}
+
+	if c.QueueSize <= 0 {
+		return fmt.Errorf("queue size %d is not positive", c.QueueSize)
+	}
+
+	if c.LeaseTimeout <= 0 {
+		return fmt.Errorf("lease timeout %v is not positive", c.LeaseTimeout)
+	}
+
+	if _, err := c.Kafka.ConfigMap(); err != nil {
+		return err
+	}
+
+	if c.Log.Level != LogInfo && c.Log.Level != LogDebug {
+		return fmt.Errorf("unknown log level %s", c.Log.Level)
+	}
+
+	return nil
+}
+
+// ConfigMap returns the settings passed through to the Kafka clients
+func (k *KafkaConfig) ConfigMap() (map[string]string, error) {
+	settings := make(map[string]string)
+	for _, s := range k.Settings {
+		i := strings.Index(s, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid kafka setting %q, expected key=value", s)
+		}
+
+		key := s[:i]
+		for _, r := range reservedKafkaSettings {
+			if key == r {
+				return nil, fmt.Errorf("kafka setting %s is set by the server", key)
+			}
+		}
+		settings[key] = s[i+1:]
+	}
+
+	return settings, nil
+}
+
+// MarshalYAML prints the lease timeout as a duration, so that a printed
+// config can be read back as a config file
+func (c Config) MarshalYAML() (interface{}, error) {
+	type config Config
+
+	return struct {
+		config       `yaml:",inline"`
+		LeaseTimeout string `yaml:"lease-timeout"`
+	}{config(c), c.LeaseTimeout.String()}, nil
+}
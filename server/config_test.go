@@ -0,0 +1,65 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := DefaultConfig()
+	valid.Name = "test"
+	valid.Kafka.Brokers = "localhost:9092"
+	valid.Kafka.Settings = []string{"security.protocol=ssl"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected a valid config, actual: %v", err)
+	}
+
+	invalid := map[string]func(c *Config){
+		"missing name":      func(c *Config) { c.Name = "" },
+		"missing brokers":   func(c *Config) { c.Kafka.Brokers = "" },
+		"unknown transport": func(c *Config) { c.Transport = "file" },
+		"listen address":    func(c *Config) { c.ListenAddress = "50000" },
+		"queue size":        func(c *Config) { c.QueueSize = 0 },
+		"lease timeout":     func(c *Config) { c.LeaseTimeout = -time.Second },
+		"kafka setting":     func(c *Config) { c.Kafka.Settings = []string{"acks"} },
+		"reserved setting":  func(c *Config) { c.Kafka.Settings = []string{"group.id=other"} },
+		"assignment strategy": func(c *Config) {
+			c.Kafka.Settings = []string{"partition.assignment.strategy=cooperative-sticky"}
+		},
+		"log level": func(c *Config) { c.Log.Level = "trace" },
+		"memory data dir": func(c *Config) {
+			c.Transport = "memory"
+			c.DataDir = "/var/lib/conductor"
+		},
+	}
+	for name, change := range invalid {
+		c := valid
+		change(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected the config with an invalid %s to be rejected", name)
+		}
+	}
+}
+
+func TestConfigYAML(t *testing.T) {
+	t.Parallel()
+
+	c := DefaultConfig()
+	c.Name = "test"
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		t.Fatalf("failed to print config: %v", err)
+	}
+
+	for _, line := range []string{"name: test", "listen-address: :50000", "lease-timeout: 30s", "  level: info"} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("expected %q in the config, actual:\n%s", line, data)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// DeadLetters is the dead-letter queue of each workflow. It holds the jobs
+// that failed without retry, and the log messages that could not be decoded,
+// until they are redriven.
+type DeadLetters struct {
+	store *Store
+}
+
+// NewDeadLetters creates the dead-letter queues persisted in the store
+func NewDeadLetters(store *Store) *DeadLetters {
+	return &DeadLetters{store: store}
+}
+
+// parseJobKey returns the workflow and the name of a job key
+func parseJobKey(key string) (string, string) {
+	i := strings.Index(key, ":")
+	if i < 0 {
+		return "", key
+	}
+
+	return key[:i], key[i+1:]
+}
+
+// Put adds an entry to the dead-letter queue of its workflow, replacing the
+// previous entry of the job
+func (d *DeadLetters) Put(dl *DeadLetter) error {
+	data, err := proto.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	return d.store.PutDeadLetter(dl.Workflow, dl.Name, data)
+}
+
+// Get returns the entry of a job, or nil if the job is not dead-lettered
+func (d *DeadLetters) Get(workflow string, name string) (*DeadLetter, error) {
+	data, err := d.store.DeadLetter(workflow, name)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	dl := &DeadLetter{}
+	if err := proto.Unmarshal(data, dl); err != nil {
+		return nil, err
+	}
+
+	return dl, nil
+}
+
+// List returns the entries of a workflow in name order
+func (d *DeadLetters) List(workflow string) ([]*DeadLetter, error) {
+	values, err := d.store.DeadLetters(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*DeadLetter, 0, len(values))
+	for _, data := range values {
+		dl := &DeadLetter{}
+		if err := proto.Unmarshal(data, dl); err != nil {
+			return nil, err
+		}
+		entries = append(entries, dl)
+	}
+
+	return entries, nil
+}
+
+// Delete removes the entry of a job
+func (d *DeadLetters) Delete(workflow string, name string) error {
+	return d.store.DeleteDeadLetter(workflow, name)
+}
+
+// sortDeadLetters sorts entries of several members by name
+func sortDeadLetters(entries []*DeadLetter) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+}
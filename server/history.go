@@ -0,0 +1,82 @@
+package server
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// History is the timeline of each job. The events of the log are recorded
+// with the WAL, and the polls and heartbeats by the member that serves them.
+type History struct {
+	store *Store
+}
+
+// NewHistory creates the job history persisted in the store
+func NewHistory(store *Store) *History {
+	return &History{store: store}
+}
+
+// logEvent returns the history event of a job message in the log
+func logEvent(job Job, timestamp time.Time) *JobEvent {
+	e := jobEvent(job, timestamp)
+
+	switch {
+	case job.Status == Job_COMPLETED:
+		e.Type = JobEvent_COMPLETED
+	case job.Status == Job_FAILED:
+		e.Type = JobEvent_FAILED
+	case job.Status == Job_CANCELLED:
+		e.Type = JobEvent_CANCELLED
+	case job.PriorityUpdate:
+		e.Type = JobEvent_PRIORITY_RAISED
+	case job.PreviousState != "":
+		e.Type = JobEvent_TRANSITIONED
+	case job.Attempt > 0 && job.Error != "":
+		e.Type = JobEvent_RETRIED
+	default:
+		e.Type = JobEvent_SUBMITTED
+	}
+
+	return e
+}
+
+// jobEvent returns an event with the state of a job
+func jobEvent(job Job, timestamp time.Time) *JobEvent {
+	return &JobEvent{
+		Time:          timestamp.UnixNano(),
+		State:         job.State,
+		PreviousState: job.PreviousState,
+		Error:         job.Error,
+		Attempt:       job.Attempt,
+		Progress:      job.Progress,
+		Partition:     job.Partition,
+		Offset:        job.Offset,
+	}
+}
+
+// Record adds an event that is not in the log to the history of a job
+func (h *History) Record(job Job, e *JobEvent) error {
+	data, err := proto.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(jobKey(job.Workflow, job.Name))
+	return h.store.PutHistory(key, time.Unix(0, e.Time), job.Partition, job.Offset, data)
+}
+
+// Get returns the events of a job in time order
+func (h *History) Get(workflow string, name string) ([]*JobEvent, error) {
+	var events []*JobEvent
+	err := h.store.ScanHistory([]byte(jobKey(workflow, name)), func(value []byte) error {
+		e := &JobEvent{}
+		if err := proto.Unmarshal(value, e); err != nil {
+			return err
+		}
+		events = append(events, e)
+		return nil
+	})
+
+	return events, err
+}
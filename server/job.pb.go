@@ -9,6 +9,32 @@ It is generated from these files:
 
 It has these top-level messages:
 	Job
+	PollRequest
+	CompleteRequest
+	FailRequest
+	LeaseRequest
+	WorkflowDefinition
+	Member
+	DeadLetter
+	DeadLetterRequest
+	DeadLetterList
+	RedriveRequest
+	BatchPollRequest
+	JobList
+	BatchCompleteRequest
+	BatchFailRequest
+	JobResult
+	BatchResult
+	JobEvent
+	JobHistoryRequest
+	JobHistory
+	GetJobRequest
+	JobInfo
+	ListJobsRequest
+	JobPage
+	CancelRequest
+	BulkCancelRequest
+	BulkCancelResult
 */
 package server
 
@@ -32,11 +58,93 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type Job_Status int32
+
+const (
+	Job_ACTIVE    Job_Status = 0
+	Job_COMPLETED Job_Status = 1
+	Job_FAILED    Job_Status = 2
+	Job_CANCELLED Job_Status = 3
+)
+
+var Job_Status_name = map[int32]string{
+	0: "ACTIVE",
+	1: "COMPLETED",
+	2: "FAILED",
+	3: "CANCELLED",
+}
+var Job_Status_value = map[string]int32{
+	"ACTIVE":    0,
+	"COMPLETED": 1,
+	"FAILED":    2,
+	"CANCELLED": 3,
+}
+
+func (x Job_Status) String() string {
+	return proto.EnumName(Job_Status_name, int32(x))
+}
+func (Job_Status) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
+
+type JobEvent_Type int32
+
+const (
+	JobEvent_SUBMITTED       JobEvent_Type = 0
+	JobEvent_POLLED          JobEvent_Type = 1
+	JobEvent_HEARTBEAT       JobEvent_Type = 2
+	JobEvent_COMPLETED       JobEvent_Type = 3
+	JobEvent_FAILED          JobEvent_Type = 4
+	JobEvent_TRANSITIONED    JobEvent_Type = 5
+	JobEvent_RETRIED         JobEvent_Type = 6
+	JobEvent_CANCELLED       JobEvent_Type = 7
+	JobEvent_PRIORITY_RAISED JobEvent_Type = 8
+)
+
+var JobEvent_Type_name = map[int32]string{
+	0: "SUBMITTED",
+	1: "POLLED",
+	2: "HEARTBEAT",
+	3: "COMPLETED",
+	4: "FAILED",
+	5: "TRANSITIONED",
+	6: "RETRIED",
+	7: "CANCELLED",
+	8: "PRIORITY_RAISED",
+}
+var JobEvent_Type_value = map[string]int32{
+	"SUBMITTED":       0,
+	"POLLED":          1,
+	"HEARTBEAT":       2,
+	"COMPLETED":       3,
+	"FAILED":          4,
+	"TRANSITIONED":    5,
+	"RETRIED":         6,
+	"CANCELLED":       7,
+	"PRIORITY_RAISED": 8,
+}
+
+func (x JobEvent_Type) String() string {
+	return proto.EnumName(JobEvent_Type_name, int32(x))
+}
+func (JobEvent_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{17, 0} }
+
 type Job struct {
-	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
-	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
-	State    string `protobuf:"bytes,3,opt,name=state" json:"state,omitempty"`
-	Data     string `protobuf:"bytes,4,opt,name=data" json:"data,omitempty"`
+	Workflow       string            `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name           string            `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	State          string            `protobuf:"bytes,3,opt,name=state" json:"state,omitempty"`
+	Data           string            `protobuf:"bytes,4,opt,name=data" json:"data,omitempty"`
+	Partition      int32             `protobuf:"varint,5,opt,name=partition" json:"partition,omitempty"`
+	Offset         int64             `protobuf:"varint,6,opt,name=offset" json:"offset,omitempty"`
+	Status         Job_Status        `protobuf:"varint,7,opt,name=status,enum=server.Job_Status" json:"status,omitempty"`
+	Error          string            `protobuf:"bytes,8,opt,name=error" json:"error,omitempty"`
+	Attempt        int32             `protobuf:"varint,9,opt,name=attempt" json:"attempt,omitempty"`
+	LeaseToken     string            `protobuf:"bytes,10,opt,name=lease_token,json=leaseToken" json:"lease_token,omitempty"`
+	Progress       string            `protobuf:"bytes,11,opt,name=progress" json:"progress,omitempty"`
+	Priority       int32             `protobuf:"varint,12,opt,name=priority" json:"priority,omitempty"`
+	NotBefore      int64             `protobuf:"varint,13,opt,name=not_before,json=notBefore" json:"not_before,omitempty"`
+	PreviousState  string            `protobuf:"bytes,14,opt,name=previous_state,json=previousState" json:"previous_state,omitempty"`
+	Labels         map[string]string `protobuf:"bytes,15,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SubmittedAt    int64             `protobuf:"varint,16,opt,name=submitted_at,json=submittedAt" json:"submitted_at,omitempty"`
+	PriorityUpdate bool              `protobuf:"varint,17,opt,name=priority_update,json=priorityUpdate" json:"priority_update,omitempty"`
 }
 
 func (m *Job) Reset()                    { *m = Job{} }
@@ -72,78 +180,1666 @@ func (m *Job) GetData() string {
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*Job)(nil), "server.Job")
+func (m *Job) GetPartition() int32 {
+	if m != nil {
+		return m.Partition
+	}
+	return 0
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *Job) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *Job) GetStatus() Job_Status {
+	if m != nil {
+		return m.Status
+	}
+	return Job_ACTIVE
+}
 
-// Client API for JobService service
+func (m *Job) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
 
-type JobServiceClient interface {
-	// Add a new job
-	AddJob(ctx context.Context, in *Job, opts ...grpc.CallOption) (*Job, error)
+func (m *Job) GetAttempt() int32 {
+	if m != nil {
+		return m.Attempt
+	}
+	return 0
 }
 
-type jobServiceClient struct {
-	cc *grpc.ClientConn
+func (m *Job) GetLeaseToken() string {
+	if m != nil {
+		return m.LeaseToken
+	}
+	return ""
 }
 
-func NewJobServiceClient(cc *grpc.ClientConn) JobServiceClient {
-	return &jobServiceClient{cc}
+func (m *Job) GetProgress() string {
+	if m != nil {
+		return m.Progress
+	}
+	return ""
 }
 
-func (c *jobServiceClient) AddJob(ctx context.Context, in *Job, opts ...grpc.CallOption) (*Job, error) {
-	out := new(Job)
-	err := grpc.Invoke(ctx, "/server.JobService/AddJob", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *Job) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
 	}
-	return out, nil
+	return 0
 }
 
-// Server API for JobService service
+func (m *Job) GetNotBefore() int64 {
+	if m != nil {
+		return m.NotBefore
+	}
+	return 0
+}
 
-type JobServiceServer interface {
-	// Add a new job
-	AddJob(context.Context, *Job) (*Job, error)
+func (m *Job) GetPreviousState() string {
+	if m != nil {
+		return m.PreviousState
+	}
+	return ""
 }
 
-func RegisterJobServiceServer(s *grpc.Server, srv JobServiceServer) {
-	s.RegisterService(&_JobService_serviceDesc, srv)
+func (m *Job) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
 }
 
-func _JobService_AddJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Job)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *Job) GetSubmittedAt() int64 {
+	if m != nil {
+		return m.SubmittedAt
 	}
-	if interceptor == nil {
-		return srv.(JobServiceServer).AddJob(ctx, in)
+	return 0
+}
+
+func (m *Job) GetPriorityUpdate() bool {
+	if m != nil {
+		return m.PriorityUpdate
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/server.JobService/AddJob",
+	return false
+}
+
+type PollRequest struct {
+	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	State    string `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	WaitMs   int32  `protobuf:"varint,3,opt,name=wait_ms,json=waitMs" json:"wait_ms,omitempty"`
+	Worker   string `protobuf:"bytes,4,opt,name=worker" json:"worker,omitempty"`
+}
+
+func (m *PollRequest) Reset()                    { *m = PollRequest{} }
+func (m *PollRequest) String() string            { return proto.CompactTextString(m) }
+func (*PollRequest) ProtoMessage()               {}
+func (*PollRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *PollRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(JobServiceServer).AddJob(ctx, req.(*Job))
+	return ""
+}
+
+func (m *PollRequest) GetState() string {
+	if m != nil {
+		return m.State
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-var _JobService_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "server.JobService",
-	HandlerType: (*JobServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "AddJob",
-			Handler:    _JobService_AddJob_Handler,
+func (m *PollRequest) GetWaitMs() int32 {
+	if m != nil {
+		return m.WaitMs
+	}
+	return 0
+}
+
+func (m *PollRequest) GetWorker() string {
+	if m != nil {
+		return m.Worker
+	}
+	return ""
+}
+
+type CompleteRequest struct {
+	Workflow   string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name       string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	NextState  string `protobuf:"bytes,3,opt,name=next_state,json=nextState" json:"next_state,omitempty"`
+	Data       string `protobuf:"bytes,4,opt,name=data" json:"data,omitempty"`
+	LeaseToken string `protobuf:"bytes,5,opt,name=lease_token,json=leaseToken" json:"lease_token,omitempty"`
+}
+
+func (m *CompleteRequest) Reset()                    { *m = CompleteRequest{} }
+func (m *CompleteRequest) String() string            { return proto.CompactTextString(m) }
+func (*CompleteRequest) ProtoMessage()               {}
+func (*CompleteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *CompleteRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *CompleteRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CompleteRequest) GetNextState() string {
+	if m != nil {
+		return m.NextState
+	}
+	return ""
+}
+
+func (m *CompleteRequest) GetData() string {
+	if m != nil {
+		return m.Data
+	}
+	return ""
+}
+
+func (m *CompleteRequest) GetLeaseToken() string {
+	if m != nil {
+		return m.LeaseToken
+	}
+	return ""
+}
+
+type FailRequest struct {
+	Workflow   string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name       string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Error      string `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	LeaseToken string `protobuf:"bytes,4,opt,name=lease_token,json=leaseToken" json:"lease_token,omitempty"`
+	Code       string `protobuf:"bytes,5,opt,name=code" json:"code,omitempty"`
+}
+
+func (m *FailRequest) Reset()                    { *m = FailRequest{} }
+func (m *FailRequest) String() string            { return proto.CompactTextString(m) }
+func (*FailRequest) ProtoMessage()               {}
+func (*FailRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *FailRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *FailRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FailRequest) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *FailRequest) GetLeaseToken() string {
+	if m != nil {
+		return m.LeaseToken
+	}
+	return ""
+}
+
+func (m *FailRequest) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+type LeaseRequest struct {
+	Workflow   string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name       string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	LeaseToken string `protobuf:"bytes,3,opt,name=lease_token,json=leaseToken" json:"lease_token,omitempty"`
+	Progress   string `protobuf:"bytes,4,opt,name=progress" json:"progress,omitempty"`
+	Worker     string `protobuf:"bytes,5,opt,name=worker" json:"worker,omitempty"`
+}
+
+func (m *LeaseRequest) Reset()                    { *m = LeaseRequest{} }
+func (m *LeaseRequest) String() string            { return proto.CompactTextString(m) }
+func (*LeaseRequest) ProtoMessage()               {}
+func (*LeaseRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *LeaseRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *LeaseRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LeaseRequest) GetLeaseToken() string {
+	if m != nil {
+		return m.LeaseToken
+	}
+	return ""
+}
+
+func (m *LeaseRequest) GetProgress() string {
+	if m != nil {
+		return m.Progress
+	}
+	return ""
+}
+
+func (m *LeaseRequest) GetWorker() string {
+	if m != nil {
+		return m.Worker
+	}
+	return ""
+}
+
+type WorkflowDefinition struct {
+	Name       string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Definition string `protobuf:"bytes,2,opt,name=definition" json:"definition,omitempty"`
+}
+
+func (m *WorkflowDefinition) Reset()                    { *m = WorkflowDefinition{} }
+func (m *WorkflowDefinition) String() string            { return proto.CompactTextString(m) }
+func (*WorkflowDefinition) ProtoMessage()               {}
+func (*WorkflowDefinition) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *WorkflowDefinition) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *WorkflowDefinition) GetDefinition() string {
+	if m != nil {
+		return m.Definition
+	}
+	return ""
+}
+
+type Member struct {
+	Address    string  `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Partitions []int32 `protobuf:"varint,2,rep,name=partitions" json:"partitions,omitempty"`
+}
+
+func (m *Member) Reset()                    { *m = Member{} }
+func (m *Member) String() string            { return proto.CompactTextString(m) }
+func (*Member) ProtoMessage()               {}
+func (*Member) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Member) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Member) GetPartitions() []int32 {
+	if m != nil {
+		return m.Partitions
+	}
+	return nil
+}
+
+type DeadLetter struct {
+	Workflow  string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name      string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	State     string `protobuf:"bytes,3,opt,name=state" json:"state,omitempty"`
+	Error     string `protobuf:"bytes,4,opt,name=error" json:"error,omitempty"`
+	Payload   []byte `protobuf:"bytes,5,opt,name=payload" json:"payload,omitempty"`
+	FailedAt  int64  `protobuf:"varint,6,opt,name=failed_at,json=failedAt" json:"failed_at,omitempty"`
+	Partition int32  `protobuf:"varint,7,opt,name=partition" json:"partition,omitempty"`
+	Offset    int64  `protobuf:"varint,8,opt,name=offset" json:"offset,omitempty"`
+}
+
+func (m *DeadLetter) Reset()                    { *m = DeadLetter{} }
+func (m *DeadLetter) String() string            { return proto.CompactTextString(m) }
+func (*DeadLetter) ProtoMessage()               {}
+func (*DeadLetter) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *DeadLetter) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *DeadLetter) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DeadLetter) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *DeadLetter) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *DeadLetter) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *DeadLetter) GetFailedAt() int64 {
+	if m != nil {
+		return m.FailedAt
+	}
+	return 0
+}
+
+func (m *DeadLetter) GetPartition() int32 {
+	if m != nil {
+		return m.Partition
+	}
+	return 0
+}
+
+func (m *DeadLetter) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+type DeadLetterRequest struct {
+	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *DeadLetterRequest) Reset()                    { *m = DeadLetterRequest{} }
+func (m *DeadLetterRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeadLetterRequest) ProtoMessage()               {}
+func (*DeadLetterRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *DeadLetterRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *DeadLetterRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type DeadLetterList struct {
+	Entries []*DeadLetter `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+}
+
+func (m *DeadLetterList) Reset()                    { *m = DeadLetterList{} }
+func (m *DeadLetterList) String() string            { return proto.CompactTextString(m) }
+func (*DeadLetterList) ProtoMessage()               {}
+func (*DeadLetterList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *DeadLetterList) GetEntries() []*DeadLetter {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type RedriveRequest struct {
+	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	State    string `protobuf:"bytes,3,opt,name=state" json:"state,omitempty"`
+}
+
+func (m *RedriveRequest) Reset()                    { *m = RedriveRequest{} }
+func (m *RedriveRequest) String() string            { return proto.CompactTextString(m) }
+func (*RedriveRequest) ProtoMessage()               {}
+func (*RedriveRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *RedriveRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *RedriveRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RedriveRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+type BatchPollRequest struct {
+	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	State    string `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	Max      int32  `protobuf:"varint,3,opt,name=max" json:"max,omitempty"`
+	WaitMs   int32  `protobuf:"varint,4,opt,name=wait_ms,json=waitMs" json:"wait_ms,omitempty"`
+	Worker   string `protobuf:"bytes,5,opt,name=worker" json:"worker,omitempty"`
+}
+
+func (m *BatchPollRequest) Reset()                    { *m = BatchPollRequest{} }
+func (m *BatchPollRequest) String() string            { return proto.CompactTextString(m) }
+func (*BatchPollRequest) ProtoMessage()               {}
+func (*BatchPollRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *BatchPollRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *BatchPollRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *BatchPollRequest) GetMax() int32 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+func (m *BatchPollRequest) GetWaitMs() int32 {
+	if m != nil {
+		return m.WaitMs
+	}
+	return 0
+}
+
+func (m *BatchPollRequest) GetWorker() string {
+	if m != nil {
+		return m.Worker
+	}
+	return ""
+}
+
+type JobList struct {
+	Jobs []*Job `protobuf:"bytes,1,rep,name=jobs" json:"jobs,omitempty"`
+}
+
+func (m *JobList) Reset()                    { *m = JobList{} }
+func (m *JobList) String() string            { return proto.CompactTextString(m) }
+func (*JobList) ProtoMessage()               {}
+func (*JobList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *JobList) GetJobs() []*Job {
+	if m != nil {
+		return m.Jobs
+	}
+	return nil
+}
+
+type BatchCompleteRequest struct {
+	Requests []*CompleteRequest `protobuf:"bytes,1,rep,name=requests" json:"requests,omitempty"`
+}
+
+func (m *BatchCompleteRequest) Reset()                    { *m = BatchCompleteRequest{} }
+func (m *BatchCompleteRequest) String() string            { return proto.CompactTextString(m) }
+func (*BatchCompleteRequest) ProtoMessage()               {}
+func (*BatchCompleteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *BatchCompleteRequest) GetRequests() []*CompleteRequest {
+	if m != nil {
+		return m.Requests
+	}
+	return nil
+}
+
+type BatchFailRequest struct {
+	Requests []*FailRequest `protobuf:"bytes,1,rep,name=requests" json:"requests,omitempty"`
+}
+
+func (m *BatchFailRequest) Reset()                    { *m = BatchFailRequest{} }
+func (m *BatchFailRequest) String() string            { return proto.CompactTextString(m) }
+func (*BatchFailRequest) ProtoMessage()               {}
+func (*BatchFailRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *BatchFailRequest) GetRequests() []*FailRequest {
+	if m != nil {
+		return m.Requests
+	}
+	return nil
+}
+
+type JobResult struct {
+	Job   *Job   `protobuf:"bytes,1,opt,name=job" json:"job,omitempty"`
+	Code  int32  `protobuf:"varint,2,opt,name=code" json:"code,omitempty"`
+	Error string `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *JobResult) Reset()                    { *m = JobResult{} }
+func (m *JobResult) String() string            { return proto.CompactTextString(m) }
+func (*JobResult) ProtoMessage()               {}
+func (*JobResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *JobResult) GetJob() *Job {
+	if m != nil {
+		return m.Job
+	}
+	return nil
+}
+
+func (m *JobResult) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *JobResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type BatchResult struct {
+	Results []*JobResult `protobuf:"bytes,1,rep,name=results" json:"results,omitempty"`
+}
+
+func (m *BatchResult) Reset()                    { *m = BatchResult{} }
+func (m *BatchResult) String() string            { return proto.CompactTextString(m) }
+func (*BatchResult) ProtoMessage()               {}
+func (*BatchResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *BatchResult) GetResults() []*JobResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type JobEvent struct {
+	Type          JobEvent_Type `protobuf:"varint,1,opt,name=type,enum=server.JobEvent_Type" json:"type,omitempty"`
+	Time          int64         `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	State         string        `protobuf:"bytes,3,opt,name=state" json:"state,omitempty"`
+	PreviousState string        `protobuf:"bytes,4,opt,name=previous_state,json=previousState" json:"previous_state,omitempty"`
+	Worker        string        `protobuf:"bytes,5,opt,name=worker" json:"worker,omitempty"`
+	Error         string        `protobuf:"bytes,6,opt,name=error" json:"error,omitempty"`
+	Attempt       int32         `protobuf:"varint,7,opt,name=attempt" json:"attempt,omitempty"`
+	Progress      string        `protobuf:"bytes,8,opt,name=progress" json:"progress,omitempty"`
+	Partition     int32         `protobuf:"varint,9,opt,name=partition" json:"partition,omitempty"`
+	Offset        int64         `protobuf:"varint,10,opt,name=offset" json:"offset,omitempty"`
+}
+
+func (m *JobEvent) Reset()                    { *m = JobEvent{} }
+func (m *JobEvent) String() string            { return proto.CompactTextString(m) }
+func (*JobEvent) ProtoMessage()               {}
+func (*JobEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *JobEvent) GetType() JobEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return JobEvent_SUBMITTED
+}
+
+func (m *JobEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *JobEvent) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *JobEvent) GetPreviousState() string {
+	if m != nil {
+		return m.PreviousState
+	}
+	return ""
+}
+
+func (m *JobEvent) GetWorker() string {
+	if m != nil {
+		return m.Worker
+	}
+	return ""
+}
+
+func (m *JobEvent) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *JobEvent) GetAttempt() int32 {
+	if m != nil {
+		return m.Attempt
+	}
+	return 0
+}
+
+func (m *JobEvent) GetProgress() string {
+	if m != nil {
+		return m.Progress
+	}
+	return ""
+}
+
+func (m *JobEvent) GetPartition() int32 {
+	if m != nil {
+		return m.Partition
+	}
+	return 0
+}
+
+func (m *JobEvent) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+type JobHistoryRequest struct {
+	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *JobHistoryRequest) Reset()                    { *m = JobHistoryRequest{} }
+func (m *JobHistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*JobHistoryRequest) ProtoMessage()               {}
+func (*JobHistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *JobHistoryRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *JobHistoryRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type JobHistory struct {
+	Events []*JobEvent `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+}
+
+func (m *JobHistory) Reset()                    { *m = JobHistory{} }
+func (m *JobHistory) String() string            { return proto.CompactTextString(m) }
+func (*JobHistory) ProtoMessage()               {}
+func (*JobHistory) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *JobHistory) GetEvents() []*JobEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type GetJobRequest struct {
+	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *GetJobRequest) Reset()                    { *m = GetJobRequest{} }
+func (m *GetJobRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetJobRequest) ProtoMessage()               {}
+func (*GetJobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *GetJobRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *GetJobRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type JobInfo struct {
+	Job            *Job  `protobuf:"bytes,1,opt,name=job" json:"job,omitempty"`
+	Leased         bool  `protobuf:"varint,2,opt,name=leased" json:"leased,omitempty"`
+	LeaseExpiresAt int64 `protobuf:"varint,3,opt,name=lease_expires_at,json=leaseExpiresAt" json:"lease_expires_at,omitempty"`
+}
+
+func (m *JobInfo) Reset()                    { *m = JobInfo{} }
+func (m *JobInfo) String() string            { return proto.CompactTextString(m) }
+func (*JobInfo) ProtoMessage()               {}
+func (*JobInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *JobInfo) GetJob() *Job {
+	if m != nil {
+		return m.Job
+	}
+	return nil
+}
+
+func (m *JobInfo) GetLeased() bool {
+	if m != nil {
+		return m.Leased
+	}
+	return false
+}
+
+func (m *JobInfo) GetLeaseExpiresAt() int64 {
+	if m != nil {
+		return m.LeaseExpiresAt
+	}
+	return 0
+}
+
+type ListJobsRequest struct {
+	Workflow        string            `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	State           string            `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	Labels          map[string]string `protobuf:"bytes,3,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SubmittedAfter  int64             `protobuf:"varint,4,opt,name=submitted_after,json=submittedAfter" json:"submitted_after,omitempty"`
+	SubmittedBefore int64             `protobuf:"varint,5,opt,name=submitted_before,json=submittedBefore" json:"submitted_before,omitempty"`
+	PageSize        int32             `protobuf:"varint,6,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	Cursor          string            `protobuf:"bytes,7,opt,name=cursor" json:"cursor,omitempty"`
+}
+
+func (m *ListJobsRequest) Reset()                    { *m = ListJobsRequest{} }
+func (m *ListJobsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListJobsRequest) ProtoMessage()               {}
+func (*ListJobsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *ListJobsRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *ListJobsRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *ListJobsRequest) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *ListJobsRequest) GetSubmittedAfter() int64 {
+	if m != nil {
+		return m.SubmittedAfter
+	}
+	return 0
+}
+
+func (m *ListJobsRequest) GetSubmittedBefore() int64 {
+	if m != nil {
+		return m.SubmittedBefore
+	}
+	return 0
+}
+
+func (m *ListJobsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListJobsRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+type JobPage struct {
+	Jobs       []*Job `protobuf:"bytes,1,rep,name=jobs" json:"jobs,omitempty"`
+	NextCursor string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor" json:"next_cursor,omitempty"`
+}
+
+func (m *JobPage) Reset()                    { *m = JobPage{} }
+func (m *JobPage) String() string            { return proto.CompactTextString(m) }
+func (*JobPage) ProtoMessage()               {}
+func (*JobPage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *JobPage) GetJobs() []*Job {
+	if m != nil {
+		return m.Jobs
+	}
+	return nil
+}
+
+func (m *JobPage) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type CancelRequest struct {
+	Workflow string `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *CancelRequest) Reset()                    { *m = CancelRequest{} }
+func (m *CancelRequest) String() string            { return proto.CompactTextString(m) }
+func (*CancelRequest) ProtoMessage()               {}
+func (*CancelRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *CancelRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *CancelRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type BulkCancelRequest struct {
+	Workflow string            `protobuf:"bytes,1,opt,name=workflow" json:"workflow,omitempty"`
+	State    string            `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	Labels   map[string]string `protobuf:"bytes,3,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DryRun   bool              `protobuf:"varint,4,opt,name=dry_run,json=dryRun" json:"dry_run,omitempty"`
+}
+
+func (m *BulkCancelRequest) Reset()                    { *m = BulkCancelRequest{} }
+func (m *BulkCancelRequest) String() string            { return proto.CompactTextString(m) }
+func (*BulkCancelRequest) ProtoMessage()               {}
+func (*BulkCancelRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *BulkCancelRequest) GetWorkflow() string {
+	if m != nil {
+		return m.Workflow
+	}
+	return ""
+}
+
+func (m *BulkCancelRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *BulkCancelRequest) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *BulkCancelRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type BulkCancelResult struct {
+	Count int32 `protobuf:"varint,1,opt,name=count" json:"count,omitempty"`
+}
+
+func (m *BulkCancelResult) Reset()                    { *m = BulkCancelResult{} }
+func (m *BulkCancelResult) String() string            { return proto.CompactTextString(m) }
+func (*BulkCancelResult) ProtoMessage()               {}
+func (*BulkCancelResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *BulkCancelResult) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Job)(nil), "server.Job")
+	proto.RegisterType((*PollRequest)(nil), "server.PollRequest")
+	proto.RegisterType((*CompleteRequest)(nil), "server.CompleteRequest")
+	proto.RegisterType((*FailRequest)(nil), "server.FailRequest")
+	proto.RegisterType((*LeaseRequest)(nil), "server.LeaseRequest")
+	proto.RegisterType((*WorkflowDefinition)(nil), "server.WorkflowDefinition")
+	proto.RegisterType((*Member)(nil), "server.Member")
+	proto.RegisterType((*DeadLetter)(nil), "server.DeadLetter")
+	proto.RegisterType((*DeadLetterRequest)(nil), "server.DeadLetterRequest")
+	proto.RegisterType((*DeadLetterList)(nil), "server.DeadLetterList")
+	proto.RegisterType((*RedriveRequest)(nil), "server.RedriveRequest")
+	proto.RegisterType((*BatchPollRequest)(nil), "server.BatchPollRequest")
+	proto.RegisterType((*JobList)(nil), "server.JobList")
+	proto.RegisterType((*BatchCompleteRequest)(nil), "server.BatchCompleteRequest")
+	proto.RegisterType((*BatchFailRequest)(nil), "server.BatchFailRequest")
+	proto.RegisterType((*JobResult)(nil), "server.JobResult")
+	proto.RegisterType((*BatchResult)(nil), "server.BatchResult")
+	proto.RegisterType((*JobEvent)(nil), "server.JobEvent")
+	proto.RegisterType((*JobHistoryRequest)(nil), "server.JobHistoryRequest")
+	proto.RegisterType((*JobHistory)(nil), "server.JobHistory")
+	proto.RegisterType((*GetJobRequest)(nil), "server.GetJobRequest")
+	proto.RegisterType((*JobInfo)(nil), "server.JobInfo")
+	proto.RegisterType((*ListJobsRequest)(nil), "server.ListJobsRequest")
+	proto.RegisterType((*JobPage)(nil), "server.JobPage")
+	proto.RegisterType((*CancelRequest)(nil), "server.CancelRequest")
+	proto.RegisterType((*BulkCancelRequest)(nil), "server.BulkCancelRequest")
+	proto.RegisterType((*BulkCancelResult)(nil), "server.BulkCancelResult")
+	proto.RegisterEnum("server.Job_Status", Job_Status_name, Job_Status_value)
+	proto.RegisterEnum("server.JobEvent_Type", JobEvent_Type_name, JobEvent_Type_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for JobService service
+
+type JobServiceClient interface {
+	// Add a new job
+	AddJob(ctx context.Context, in *Job, opts ...grpc.CallOption) (*Job, error)
+	// Poll a job that is ready in a workflow state
+	PollJob(ctx context.Context, in *PollRequest, opts ...grpc.CallOption) (*Job, error)
+	// Complete a polled job, optionally moving it to the next state
+	CompleteJob(ctx context.Context, in *CompleteRequest, opts ...grpc.CallOption) (*Job, error)
+	// Fail a polled job
+	FailJob(ctx context.Context, in *FailRequest, opts ...grpc.CallOption) (*Job, error)
+	// Extend the lease of a polled job
+	ExtendLease(ctx context.Context, in *LeaseRequest, opts ...grpc.CallOption) (*Job, error)
+	// Create or replace a workflow definition
+	PutWorkflow(ctx context.Context, in *WorkflowDefinition, opts ...grpc.CallOption) (*WorkflowDefinition, error)
+	// Get a workflow definition by name
+	GetWorkflow(ctx context.Context, in *WorkflowDefinition, opts ...grpc.CallOption) (*WorkflowDefinition, error)
+	// List the dead-lettered jobs of a workflow, without their payloads
+	ListDeadLetters(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*DeadLetterList, error)
+	// Get a dead-lettered job with its payload
+	GetDeadLetter(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error)
+	// Publish a dead-lettered job again, in a chosen state
+	RedriveJob(ctx context.Context, in *RedriveRequest, opts ...grpc.CallOption) (*Job, error)
+	// Poll up to a number of jobs that are ready in a workflow state, each
+	// with its own lease
+	PollJobs(ctx context.Context, in *BatchPollRequest, opts ...grpc.CallOption) (*JobList, error)
+	// Complete polled jobs, with a result for each job
+	CompleteJobs(ctx context.Context, in *BatchCompleteRequest, opts ...grpc.CallOption) (*BatchResult, error)
+	// Fail polled jobs, with a result for each job
+	FailJobs(ctx context.Context, in *BatchFailRequest, opts ...grpc.CallOption) (*BatchResult, error)
+	// Get the events of a job in time order
+	GetJobHistory(ctx context.Context, in *JobHistoryRequest, opts ...grpc.CallOption) (*JobHistory, error)
+	// Get the current state of a job and its lease
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*JobInfo, error)
+	// List the jobs of a workflow in submit time order, a page at a time
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*JobPage, error)
+	// Cancel a queued, delayed or polled job
+	CancelJob(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*Job, error)
+	// Cancel the active jobs of a workflow that match a filter
+	CancelJobs(ctx context.Context, in *BulkCancelRequest, opts ...grpc.CallOption) (*BulkCancelResult, error)
+}
+
+type jobServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewJobServiceClient(cc *grpc.ClientConn) JobServiceClient {
+	return &jobServiceClient{cc}
+}
+
+func (c *jobServiceClient) AddJob(ctx context.Context, in *Job, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := grpc.Invoke(ctx, "/server.JobService/AddJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) PollJob(ctx context.Context, in *PollRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := grpc.Invoke(ctx, "/server.JobService/PollJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) CompleteJob(ctx context.Context, in *CompleteRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := grpc.Invoke(ctx, "/server.JobService/CompleteJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) FailJob(ctx context.Context, in *FailRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := grpc.Invoke(ctx, "/server.JobService/FailJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) ExtendLease(ctx context.Context, in *LeaseRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := grpc.Invoke(ctx, "/server.JobService/ExtendLease", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) PutWorkflow(ctx context.Context, in *WorkflowDefinition, opts ...grpc.CallOption) (*WorkflowDefinition, error) {
+	out := new(WorkflowDefinition)
+	err := grpc.Invoke(ctx, "/server.JobService/PutWorkflow", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) GetWorkflow(ctx context.Context, in *WorkflowDefinition, opts ...grpc.CallOption) (*WorkflowDefinition, error) {
+	out := new(WorkflowDefinition)
+	err := grpc.Invoke(ctx, "/server.JobService/GetWorkflow", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) ListDeadLetters(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*DeadLetterList, error) {
+	out := new(DeadLetterList)
+	err := grpc.Invoke(ctx, "/server.JobService/ListDeadLetters", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) GetDeadLetter(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error) {
+	out := new(DeadLetter)
+	err := grpc.Invoke(ctx, "/server.JobService/GetDeadLetter", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) RedriveJob(ctx context.Context, in *RedriveRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := grpc.Invoke(ctx, "/server.JobService/RedriveJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) PollJobs(ctx context.Context, in *BatchPollRequest, opts ...grpc.CallOption) (*JobList, error) {
+	out := new(JobList)
+	err := grpc.Invoke(ctx, "/server.JobService/PollJobs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) CompleteJobs(ctx context.Context, in *BatchCompleteRequest, opts ...grpc.CallOption) (*BatchResult, error) {
+	out := new(BatchResult)
+	err := grpc.Invoke(ctx, "/server.JobService/CompleteJobs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) FailJobs(ctx context.Context, in *BatchFailRequest, opts ...grpc.CallOption) (*BatchResult, error) {
+	out := new(BatchResult)
+	err := grpc.Invoke(ctx, "/server.JobService/FailJobs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) GetJobHistory(ctx context.Context, in *JobHistoryRequest, opts ...grpc.CallOption) (*JobHistory, error) {
+	out := new(JobHistory)
+	err := grpc.Invoke(ctx, "/server.JobService/GetJobHistory", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*JobInfo, error) {
+	out := new(JobInfo)
+	err := grpc.Invoke(ctx, "/server.JobService/GetJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*JobPage, error) {
+	out := new(JobPage)
+	err := grpc.Invoke(ctx, "/server.JobService/ListJobs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) CancelJob(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := grpc.Invoke(ctx, "/server.JobService/CancelJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobServiceClient) CancelJobs(ctx context.Context, in *BulkCancelRequest, opts ...grpc.CallOption) (*BulkCancelResult, error) {
+	out := new(BulkCancelResult)
+	err := grpc.Invoke(ctx, "/server.JobService/CancelJobs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for JobService service
+
+type JobServiceServer interface {
+	// Add a new job
+	AddJob(context.Context, *Job) (*Job, error)
+	// Poll a job that is ready in a workflow state
+	PollJob(context.Context, *PollRequest) (*Job, error)
+	// Complete a polled job, optionally moving it to the next state
+	CompleteJob(context.Context, *CompleteRequest) (*Job, error)
+	// Fail a polled job
+	FailJob(context.Context, *FailRequest) (*Job, error)
+	// Extend the lease of a polled job
+	ExtendLease(context.Context, *LeaseRequest) (*Job, error)
+	// Create or replace a workflow definition
+	PutWorkflow(context.Context, *WorkflowDefinition) (*WorkflowDefinition, error)
+	// Get a workflow definition by name
+	GetWorkflow(context.Context, *WorkflowDefinition) (*WorkflowDefinition, error)
+	// List the dead-lettered jobs of a workflow, without their payloads
+	ListDeadLetters(context.Context, *DeadLetterRequest) (*DeadLetterList, error)
+	// Get a dead-lettered job with its payload
+	GetDeadLetter(context.Context, *DeadLetterRequest) (*DeadLetter, error)
+	// Publish a dead-lettered job again, in a chosen state
+	RedriveJob(context.Context, *RedriveRequest) (*Job, error)
+	// Poll up to a number of jobs that are ready in a workflow state, each
+	// with its own lease
+	PollJobs(context.Context, *BatchPollRequest) (*JobList, error)
+	// Complete polled jobs, with a result for each job
+	CompleteJobs(context.Context, *BatchCompleteRequest) (*BatchResult, error)
+	// Fail polled jobs, with a result for each job
+	FailJobs(context.Context, *BatchFailRequest) (*BatchResult, error)
+	// Get the events of a job in time order
+	GetJobHistory(context.Context, *JobHistoryRequest) (*JobHistory, error)
+	// Get the current state of a job and its lease
+	GetJob(context.Context, *GetJobRequest) (*JobInfo, error)
+	// List the jobs of a workflow in submit time order, a page at a time
+	ListJobs(context.Context, *ListJobsRequest) (*JobPage, error)
+	// Cancel a queued, delayed or polled job
+	CancelJob(context.Context, *CancelRequest) (*Job, error)
+	// Cancel the active jobs of a workflow that match a filter
+	CancelJobs(context.Context, *BulkCancelRequest) (*BulkCancelResult, error)
+}
+
+func RegisterJobServiceServer(s *grpc.Server, srv JobServiceServer) {
+	s.RegisterService(&_JobService_serviceDesc, srv)
+}
+
+func _JobService_AddJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Job)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).AddJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/AddJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).AddJob(ctx, req.(*Job))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_PollJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).PollJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/PollJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).PollJob(ctx, req.(*PollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_CompleteJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).CompleteJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/CompleteJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).CompleteJob(ctx, req.(*CompleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_FailJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).FailJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/FailJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).FailJob(ctx, req.(*FailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_ExtendLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).ExtendLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/ExtendLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).ExtendLease(ctx, req.(*LeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_PutWorkflow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowDefinition)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).PutWorkflow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/PutWorkflow",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).PutWorkflow(ctx, req.(*WorkflowDefinition))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_GetWorkflow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkflowDefinition)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).GetWorkflow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/GetWorkflow",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).GetWorkflow(ctx, req.(*WorkflowDefinition))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/ListDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).ListDeadLetters(ctx, req.(*DeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_GetDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).GetDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/GetDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).GetDeadLetter(ctx, req.(*DeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_RedriveJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedriveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).RedriveJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/RedriveJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).RedriveJob(ctx, req.(*RedriveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_PollJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchPollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).PollJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/PollJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).PollJobs(ctx, req.(*BatchPollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_CompleteJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCompleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).CompleteJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/CompleteJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).CompleteJobs(ctx, req.(*BatchCompleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_FailJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchFailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).FailJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/FailJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).FailJobs(ctx, req.(*BatchFailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_GetJobHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).GetJobHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/GetJobHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).GetJobHistory(ctx, req.(*JobHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/GetJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/ListJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/CancelJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).CancelJob(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobService_CancelJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkCancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobServiceServer).CancelJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.JobService/CancelJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobServiceServer).CancelJobs(ctx, req.(*BulkCancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _JobService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "server.JobService",
+	HandlerType: (*JobServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddJob",
+			Handler:    _JobService_AddJob_Handler,
+		},
+		{
+			MethodName: "PollJob",
+			Handler:    _JobService_PollJob_Handler,
+		},
+		{
+			MethodName: "CompleteJob",
+			Handler:    _JobService_CompleteJob_Handler,
+		},
+		{
+			MethodName: "FailJob",
+			Handler:    _JobService_FailJob_Handler,
+		},
+		{
+			MethodName: "ExtendLease",
+			Handler:    _JobService_ExtendLease_Handler,
+		},
+		{
+			MethodName: "PutWorkflow",
+			Handler:    _JobService_PutWorkflow_Handler,
+		},
+		{
+			MethodName: "GetWorkflow",
+			Handler:    _JobService_GetWorkflow_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _JobService_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "GetDeadLetter",
+			Handler:    _JobService_GetDeadLetter_Handler,
+		},
+		{
+			MethodName: "RedriveJob",
+			Handler:    _JobService_RedriveJob_Handler,
+		},
+		{
+			MethodName: "PollJobs",
+			Handler:    _JobService_PollJobs_Handler,
+		},
+		{
+			MethodName: "CompleteJobs",
+			Handler:    _JobService_CompleteJobs_Handler,
+		},
+		{
+			MethodName: "FailJobs",
+			Handler:    _JobService_FailJobs_Handler,
+		},
+		{
+			MethodName: "GetJobHistory",
+			Handler:    _JobService_GetJobHistory_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _JobService_GetJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _JobService_ListJobs_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _JobService_CancelJob_Handler,
+		},
+		{
+			MethodName: "CancelJobs",
+			Handler:    _JobService_CancelJobs_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
@@ -153,15 +1849,106 @@ var _JobService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("job.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 152 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xcc, 0xca, 0x4f, 0xd2,
-	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2b, 0x4e, 0x2d, 0x2a, 0x4b, 0x2d, 0x52, 0x8a, 0xe7,
-	0x62, 0xf6, 0xca, 0x4f, 0x12, 0x92, 0xe2, 0xe2, 0x28, 0xcf, 0x2f, 0xca, 0x4e, 0xcb, 0xc9, 0x2f,
-	0x97, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x82, 0xf3, 0x85, 0x84, 0xb8, 0x58, 0xf2, 0x12, 0x73,
-	0x53, 0x25, 0x98, 0xc0, 0xe2, 0x60, 0xb6, 0x90, 0x08, 0x17, 0x6b, 0x71, 0x49, 0x62, 0x49, 0xaa,
-	0x04, 0x33, 0x58, 0x10, 0xc2, 0x01, 0xa9, 0x4c, 0x49, 0x2c, 0x49, 0x94, 0x60, 0x81, 0xa8, 0x04,
-	0xb1, 0x8d, 0x8c, 0xb8, 0xb8, 0xbc, 0xf2, 0x93, 0x82, 0x53, 0x8b, 0xca, 0x32, 0x93, 0x53, 0x85,
-	0x54, 0xb8, 0xd8, 0x1c, 0x53, 0x52, 0x40, 0x36, 0x72, 0xeb, 0x41, 0x5c, 0xa0, 0xe7, 0x95, 0x9f,
-	0x24, 0x85, 0xcc, 0x51, 0x62, 0x48, 0x62, 0x03, 0xbb, 0xd1, 0x18, 0x10, 0x00, 0x00, 0xff, 0xff,
-	0x10, 0xb5, 0x3d, 0xbe, 0xb0, 0x00, 0x00, 0x00,
+	// 1601 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0x6d, 0x73, 0xd3, 0xc6,
+	0x13, 0xb7, 0x2c, 0x5b, 0xb6, 0xd7, 0x89, 0xa3, 0x1c, 0xf9, 0x83, 0x30, 0xf0, 0x27, 0x55, 0xcb,
+	0x60, 0x68, 0x1b, 0xa6, 0x61, 0x4a, 0x5b, 0x98, 0x52, 0x1c, 0x47, 0x80, 0x43, 0x42, 0x32, 0x67,
+	0x43, 0xa7, 0xaf, 0x3c, 0x72, 0x74, 0x4e, 0x95, 0x38, 0x3e, 0x57, 0x3a, 0x87, 0x98, 0x2f, 0x50,
+	0x5e, 0xf5, 0x4d, 0xa7, 0x1f, 0xa3, 0x2f, 0xfa, 0x1d, 0xfa, 0x15, 0x3a, 0xd3, 0x8f, 0xd3, 0xb9,
+	0xd3, 0x83, 0x4f, 0xb2, 0x1d, 0x98, 0x84, 0x77, 0xb7, 0x7b, 0x7b, 0xbb, 0xab, 0x7d, 0xf8, 0xed,
+	0xda, 0x50, 0x3a, 0xa4, 0xdd, 0xb5, 0xa1, 0x47, 0x19, 0x45, 0x9a, 0x4f, 0xbc, 0x13, 0xe2, 0x99,
+	0xef, 0xf2, 0xa0, 0x6e, 0xd1, 0x2e, 0xaa, 0x42, 0xf1, 0x0d, 0xf5, 0x8e, 0x7a, 0x7d, 0xfa, 0xc6,
+	0x50, 0x56, 0x95, 0x5a, 0x09, 0xc7, 0x34, 0x42, 0x90, 0x1b, 0xd8, 0xc7, 0xc4, 0xc8, 0x0a, 0xbe,
+	0x38, 0xa3, 0x15, 0xc8, 0xfb, 0xcc, 0x66, 0xc4, 0x50, 0x05, 0x33, 0x20, 0xb8, 0xa4, 0x63, 0x33,
+	0xdb, 0xc8, 0x05, 0x92, 0xfc, 0x8c, 0xae, 0x43, 0x69, 0x68, 0x7b, 0xcc, 0x65, 0x2e, 0x1d, 0x18,
+	0xf9, 0x55, 0xa5, 0x96, 0xc7, 0x13, 0x06, 0xba, 0x0c, 0x1a, 0xed, 0xf5, 0x7c, 0xc2, 0x0c, 0x6d,
+	0x55, 0xa9, 0xa9, 0x38, 0xa4, 0xd0, 0x5d, 0xd0, 0xb8, 0xca, 0x91, 0x6f, 0x14, 0x56, 0x95, 0x5a,
+	0x65, 0x1d, 0xad, 0x05, 0x0e, 0xaf, 0x6d, 0xd1, 0xee, 0x5a, 0x4b, 0xdc, 0xe0, 0x50, 0x82, 0xfb,
+	0x42, 0x3c, 0x8f, 0x7a, 0x46, 0x31, 0xf0, 0x45, 0x10, 0xc8, 0x80, 0x82, 0xcd, 0x18, 0x39, 0x1e,
+	0x32, 0xa3, 0x24, 0xac, 0x46, 0x24, 0xba, 0x09, 0xe5, 0x3e, 0xb1, 0x7d, 0xd2, 0x61, 0xf4, 0x88,
+	0x0c, 0x0c, 0x10, 0xaf, 0x40, 0xb0, 0xda, 0x9c, 0xc3, 0x83, 0x31, 0xf4, 0xe8, 0x81, 0x47, 0x7c,
+	0xdf, 0x28, 0x07, 0xc1, 0x88, 0xe8, 0xe0, 0xce, 0xa5, 0x9e, 0xcb, 0xc6, 0xc6, 0x82, 0xd0, 0x1b,
+	0xd3, 0xe8, 0x06, 0xc0, 0x80, 0xb2, 0x4e, 0x97, 0xf4, 0xa8, 0x47, 0x8c, 0x45, 0xf1, 0x41, 0xa5,
+	0x01, 0x65, 0x1b, 0x82, 0x81, 0x6e, 0x41, 0x65, 0xe8, 0x91, 0x13, 0x97, 0x8e, 0xfc, 0x4e, 0x10,
+	0xbc, 0x8a, 0x50, 0xbe, 0x18, 0x71, 0x5b, 0x22, 0x88, 0xf7, 0x40, 0xeb, 0xdb, 0x5d, 0xd2, 0xf7,
+	0x8d, 0xa5, 0x55, 0xb5, 0x56, 0x5e, 0xbf, 0x22, 0x7f, 0xfa, 0xb6, 0xb8, 0xb1, 0x06, 0xcc, 0x1b,
+	0xe3, 0x50, 0x0c, 0x7d, 0x02, 0x0b, 0xfe, 0xa8, 0x7b, 0xec, 0x32, 0x46, 0x9c, 0x8e, 0xcd, 0x0c,
+	0x5d, 0x18, 0x2e, 0xc7, 0xbc, 0x3a, 0x43, 0xb7, 0x61, 0x29, 0xf2, 0xb2, 0x33, 0x1a, 0x3a, 0xdc,
+	0xf6, 0xf2, 0xaa, 0x52, 0x2b, 0xe2, 0x4a, 0xc4, 0x7e, 0x25, 0xb8, 0xd5, 0xef, 0xa0, 0x2c, 0x99,
+	0x40, 0x3a, 0xa8, 0x47, 0x64, 0x1c, 0x56, 0x04, 0x3f, 0xf2, 0x60, 0x9f, 0xd8, 0xfd, 0x51, 0x54,
+	0x0d, 0x01, 0xf1, 0x30, 0xfb, 0xad, 0x62, 0x3e, 0x06, 0x2d, 0x48, 0x0c, 0x02, 0xd0, 0xea, 0x8d,
+	0x76, 0xf3, 0xb5, 0xa5, 0x67, 0xd0, 0x22, 0x94, 0x1a, 0xbb, 0x3b, 0x7b, 0xdb, 0x56, 0xdb, 0xda,
+	0xd4, 0x15, 0x7e, 0xf5, 0xb4, 0xde, 0xdc, 0xb6, 0x36, 0xf5, 0xac, 0xb8, 0xaa, 0xbf, 0x6c, 0x58,
+	0xdb, 0x9c, 0x54, 0xcd, 0x21, 0x94, 0xf7, 0x68, 0xbf, 0x8f, 0xc9, 0x2f, 0x23, 0xe2, 0xb3, 0x33,
+	0x2b, 0x32, 0xae, 0xbe, 0xac, 0x5c, 0x7d, 0x57, 0xa0, 0xf0, 0xc6, 0x76, 0x59, 0xe7, 0xd8, 0x17,
+	0x55, 0x99, 0xc7, 0x1a, 0x27, 0x77, 0x7c, 0x5e, 0x64, 0xfc, 0x29, 0xf1, 0xc2, 0xc2, 0x0c, 0x29,
+	0xf3, 0x0f, 0x05, 0x96, 0x1a, 0xf4, 0x78, 0xd8, 0x27, 0x8c, 0x7c, 0x88, 0xd9, 0x59, 0x8d, 0xc0,
+	0x73, 0x4e, 0x4e, 0x59, 0x47, 0xee, 0x86, 0x12, 0xe7, 0xb4, 0xe6, 0x76, 0x44, 0xaa, 0xfe, 0xf2,
+	0xe9, 0xfa, 0x33, 0xdf, 0x29, 0x50, 0x7e, 0x6a, 0xbb, 0xfd, 0xf3, 0xfa, 0x14, 0x37, 0x84, 0x2a,
+	0x37, 0x44, 0xca, 0x6c, 0x6e, 0xaa, 0xec, 0x11, 0xe4, 0xf6, 0xa9, 0x43, 0x42, 0x87, 0xc4, 0xd9,
+	0xfc, 0x5d, 0x81, 0x85, 0x6d, 0x2e, 0x72, 0x5e, 0x5f, 0x52, 0x56, 0xd5, 0x33, 0x9b, 0x2d, 0x97,
+	0x6a, 0xb6, 0x49, 0xe2, 0xf2, 0x89, 0xc4, 0x3d, 0x07, 0xf4, 0x63, 0x68, 0x74, 0x93, 0xf4, 0xdc,
+	0x41, 0x80, 0x25, 0x91, 0x79, 0x45, 0x32, 0xff, 0x7f, 0x00, 0x27, 0x96, 0x08, 0x1d, 0x93, 0x38,
+	0xe6, 0x06, 0x68, 0x3b, 0xe4, 0xb8, 0x4b, 0x02, 0xbc, 0x70, 0x1c, 0xe1, 0x46, 0xa0, 0x20, 0x22,
+	0xb9, 0x8e, 0x18, 0xb0, 0x7c, 0x23, 0xbb, 0xaa, 0xd6, 0xf2, 0x58, 0xe2, 0x98, 0xff, 0x28, 0x00,
+	0x9b, 0xc4, 0x76, 0xb6, 0x09, 0x63, 0xc4, 0xfb, 0x48, 0x50, 0x1a, 0xe7, 0x30, 0x97, 0x02, 0xb5,
+	0xa1, 0x3d, 0xee, 0x53, 0xdb, 0x11, 0x11, 0x59, 0xc0, 0x11, 0x89, 0xae, 0x41, 0xa9, 0x67, 0xbb,
+	0xfd, 0x00, 0x01, 0x02, 0x2c, 0x2d, 0x06, 0x8c, 0x3a, 0x4b, 0x62, 0x70, 0x61, 0x3e, 0x06, 0x17,
+	0x65, 0x0c, 0x36, 0x1b, 0xb0, 0x3c, 0xf9, 0xac, 0x73, 0xe6, 0xdf, 0x7c, 0x0c, 0x95, 0x89, 0x92,
+	0x6d, 0xd7, 0x67, 0xe8, 0x0b, 0x28, 0x90, 0x01, 0xf3, 0x5c, 0xc2, 0x03, 0xcd, 0x01, 0x2e, 0xc6,
+	0x76, 0xc9, 0x5a, 0x24, 0x62, 0xbe, 0x86, 0x0a, 0x26, 0x8e, 0xe7, 0x9e, 0x90, 0x0b, 0x74, 0xc3,
+	0x74, 0x7c, 0xcd, 0x5f, 0x15, 0xd0, 0x37, 0x6c, 0xb6, 0xff, 0xf3, 0xc5, 0x30, 0x47, 0x07, 0xf5,
+	0xd8, 0x3e, 0x0d, 0xf1, 0x86, 0x1f, 0x65, 0x14, 0xca, 0xcd, 0x41, 0xa1, 0x64, 0x31, 0xdf, 0x85,
+	0xc2, 0x16, 0xed, 0x8a, 0xd0, 0xdc, 0x84, 0xdc, 0x21, 0xed, 0x46, 0x71, 0x29, 0x4b, 0xc0, 0x8f,
+	0xc5, 0x85, 0xf9, 0x02, 0x56, 0x84, 0xd3, 0x69, 0xd4, 0xba, 0x0f, 0x45, 0x2f, 0x38, 0x46, 0x8f,
+	0xe3, 0xa9, 0x91, 0x12, 0xc5, 0xb1, 0xa0, 0xd9, 0x08, 0x23, 0x20, 0x43, 0xcd, 0xbd, 0x29, 0x45,
+	0x97, 0x22, 0x45, 0x92, 0x98, 0xa4, 0xa4, 0x0d, 0x25, 0xee, 0x1e, 0xf1, 0x47, 0x7d, 0x86, 0x6e,
+	0x80, 0x7a, 0x48, 0xbb, 0x22, 0x74, 0x29, 0xf7, 0x39, 0x3f, 0x06, 0x98, 0xac, 0x88, 0x8b, 0x38,
+	0xcf, 0xc6, 0x2a, 0xf3, 0x21, 0x94, 0x85, 0x6b, 0xa1, 0xde, 0xcf, 0xa1, 0xe0, 0x89, 0x53, 0xe4,
+	0xd4, 0xb2, 0xac, 0x5b, 0xdc, 0xe0, 0x48, 0xc2, 0xfc, 0x4b, 0x85, 0xe2, 0x16, 0xed, 0x5a, 0x27,
+	0x64, 0xc0, 0xd0, 0x1d, 0xc8, 0xb1, 0xf1, 0x30, 0xc0, 0x84, 0xca, 0xfa, 0xff, 0xa4, 0x67, 0xe2,
+	0x7e, 0xad, 0x3d, 0x1e, 0x12, 0x2c, 0x44, 0xb8, 0x77, 0xcc, 0x0d, 0x6b, 0x47, 0xc5, 0xe2, 0x3c,
+	0xa7, 0x37, 0xa7, 0x07, 0x79, 0x6e, 0xd6, 0x20, 0x9f, 0x93, 0xf0, 0xc9, 0x27, 0x6b, 0x73, 0xf6,
+	0x95, 0x42, 0x72, 0x5f, 0x91, 0x11, 0xb2, 0x98, 0x42, 0xc8, 0x44, 0x67, 0x97, 0xe6, 0x77, 0x36,
+	0x24, 0x3a, 0xfb, 0x37, 0x05, 0x72, 0xfc, 0xcb, 0xf9, 0x08, 0x6e, 0xbd, 0xda, 0xd8, 0x69, 0xb6,
+	0xf9, 0x74, 0xce, 0xf0, 0xe9, 0xbc, 0xb7, 0x2b, 0xc6, 0xb1, 0xc2, 0xaf, 0x9e, 0x5b, 0x75, 0xdc,
+	0xde, 0xb0, 0xea, 0x6d, 0x3d, 0x9b, 0x9c, 0xe3, 0xaa, 0x34, 0xc7, 0x73, 0x48, 0x87, 0x85, 0x36,
+	0xae, 0xbf, 0x6c, 0x35, 0xdb, 0xcd, 0xdd, 0x97, 0xd6, 0xa6, 0x9e, 0x47, 0x65, 0x28, 0x60, 0xab,
+	0x8d, 0x9b, 0xd6, 0xa6, 0xae, 0x25, 0xc7, 0x7c, 0x01, 0x5d, 0x82, 0xa5, 0x3d, 0xdc, 0xdc, 0xc5,
+	0xcd, 0xf6, 0x4f, 0x1d, 0x5c, 0x6f, 0xb6, 0xac, 0x4d, 0xbd, 0xc8, 0xa1, 0x66, 0x8b, 0x76, 0x9f,
+	0xbb, 0x3e, 0xa3, 0xde, 0xf8, 0xbc, 0x50, 0xf3, 0x00, 0x60, 0xa2, 0x04, 0xd5, 0x40, 0x23, 0x3c,
+	0xc5, 0x51, 0xc9, 0xe8, 0xe9, 0xdc, 0xe3, 0xf0, 0xde, 0xfc, 0x01, 0x16, 0x9f, 0x11, 0x26, 0x2a,
+	0xe9, 0x7c, 0x86, 0x0f, 0x45, 0x07, 0x37, 0x07, 0x3d, 0xfa, 0xbe, 0x0e, 0xb8, 0x0c, 0x9a, 0x18,
+	0x7d, 0x8e, 0x78, 0x5f, 0xc4, 0x21, 0x85, 0x6a, 0xa0, 0x07, 0x53, 0x92, 0x9c, 0x0e, 0x5d, 0x8f,
+	0xf8, 0x1c, 0xc4, 0x55, 0x91, 0xb2, 0x8a, 0xe0, 0x5b, 0x01, 0xbb, 0xce, 0xcc, 0xbf, 0xb3, 0xb0,
+	0xc4, 0xb1, 0x62, 0x8b, 0x76, 0xfd, 0xf3, 0xc3, 0xd6, 0xa3, 0x78, 0xc7, 0x54, 0x45, 0x70, 0x3e,
+	0x8d, 0x3c, 0x4d, 0xa9, 0x9e, 0xb9, 0x6f, 0xde, 0x86, 0x25, 0x69, 0xdf, 0xec, 0xb1, 0x70, 0xaf,
+	0x52, 0x71, 0x65, 0xb2, 0x72, 0x72, 0x2e, 0xba, 0x03, 0xfa, 0x44, 0x30, 0xdc, 0x8a, 0xf3, 0x42,
+	0x72, 0xa2, 0x20, 0xdc, 0x8d, 0xaf, 0xf1, 0x3a, 0x3e, 0x20, 0x1d, 0xdf, 0x7d, 0x4b, 0x0c, 0x2d,
+	0xdc, 0xab, 0xed, 0x03, 0xd2, 0x72, 0xdf, 0x8a, 0x46, 0xda, 0x1f, 0x79, 0x3e, 0xf5, 0x44, 0x67,
+	0x94, 0x70, 0x48, 0x5d, 0x64, 0x59, 0x7d, 0x21, 0x52, 0xb6, 0x67, 0x1f, 0x90, 0xf7, 0x82, 0x2e,
+	0x5f, 0x61, 0xc4, 0x8a, 0x17, 0xfa, 0x10, 0xe8, 0x12, 0x5b, 0x5f, 0x43, 0x70, 0x78, 0x01, 0x35,
+	0xec, 0xc1, 0x3e, 0x39, 0xef, 0xc2, 0x66, 0xfe, 0xab, 0xc0, 0xf2, 0xc6, 0xa8, 0x7f, 0xf4, 0xe1,
+	0x5a, 0x66, 0xa7, 0xf5, 0xfb, 0x54, 0x5a, 0x6f, 0x45, 0x1f, 0x33, 0xa5, 0x7c, 0x66, 0x62, 0xaf,
+	0x40, 0xc1, 0xf1, 0xc6, 0x1d, 0x6f, 0x14, 0x6c, 0x87, 0x45, 0xac, 0x39, 0xde, 0x18, 0x8f, 0x06,
+	0x17, 0x09, 0x74, 0x0d, 0x74, 0xd9, 0xb8, 0x80, 0xf3, 0x15, 0xc8, 0xef, 0xd3, 0xd1, 0x80, 0x09,
+	0x0d, 0x79, 0x1c, 0x10, 0xeb, 0x7f, 0x16, 0x45, 0xff, 0xb6, 0x88, 0x77, 0xe2, 0xee, 0x13, 0xf4,
+	0x19, 0x68, 0x75, 0xc7, 0xe1, 0xbf, 0x4d, 0xe5, 0x94, 0x54, 0x65, 0xc2, 0xcc, 0xa0, 0x2f, 0xa1,
+	0xc0, 0x07, 0x38, 0x17, 0x8b, 0x07, 0x95, 0x34, 0xd1, 0xd3, 0xe2, 0x5f, 0x43, 0x39, 0x9a, 0x87,
+	0xfc, 0xc9, 0xbc, 0x21, 0x39, 0xc3, 0x0a, 0x9f, 0x7e, 0x09, 0x2b, 0xd2, 0x38, 0x4c, 0x8b, 0xaf,
+	0x43, 0xd9, 0x3a, 0x65, 0x64, 0xe0, 0x88, 0xcd, 0x19, 0xad, 0xc4, 0xcd, 0x25, 0x2d, 0xd2, 0xe9,
+	0x37, 0xcf, 0xa0, 0xbc, 0x37, 0x62, 0xd1, 0x56, 0x8b, 0xaa, 0xd1, 0xed, 0xf4, 0x9e, 0x5b, 0x3d,
+	0xe3, 0x2e, 0x50, 0xf4, 0x8c, 0x7c, 0x0c, 0x45, 0x4f, 0x03, 0xa0, 0x99, 0x2c, 0x65, 0x3e, 0xba,
+	0x3a, 0x63, 0x53, 0x0b, 0x3f, 0xe7, 0xf2, 0xf4, 0x15, 0x7f, 0x6d, 0x66, 0xd0, 0x13, 0x01, 0xaf,
+	0x13, 0xf6, 0x59, 0x5a, 0x66, 0xac, 0x82, 0x66, 0x06, 0xdd, 0x07, 0x08, 0x77, 0x40, 0x9e, 0x81,
+	0xd8, 0x52, 0x72, 0x2f, 0x4c, 0x07, 0xf4, 0x1b, 0x28, 0x86, 0x95, 0xe1, 0x23, 0x23, 0xee, 0x83,
+	0xd4, 0xc6, 0x57, 0x5d, 0x92, 0x1e, 0x85, 0xfe, 0x36, 0x60, 0x41, 0xaa, 0x11, 0x1f, 0x5d, 0x4f,
+	0x3c, 0x4e, 0x57, 0xca, 0xa5, 0xc4, 0x6d, 0x50, 0xe0, 0x66, 0x06, 0x3d, 0x82, 0x62, 0x58, 0x31,
+	0x69, 0xeb, 0x72, 0xdd, 0xcc, 0x79, 0xfc, 0x24, 0x1a, 0x48, 0xd1, 0x2c, 0xbb, 0x2a, 0x79, 0x99,
+	0x1c, 0x92, 0x55, 0x34, 0x7d, 0x25, 0x2a, 0x50, 0x0b, 0x34, 0xa0, 0x78, 0xe5, 0x49, 0x8c, 0xb8,
+	0xc4, 0x77, 0xf3, 0xc1, 0x65, 0x66, 0xd0, 0x03, 0x28, 0x46, 0xe8, 0x3f, 0x69, 0x8c, 0xd4, 0x3c,
+	0x48, 0xbc, 0xe3, 0xe8, 0x69, 0x66, 0xd0, 0x57, 0x50, 0x0a, 0xba, 0x3b, 0x61, 0x2e, 0x81, 0x36,
+	0xe9, 0xdc, 0x34, 0x00, 0xe2, 0x27, 0x52, 0x55, 0x4d, 0xa1, 0x54, 0xd5, 0x98, 0x75, 0x15, 0x44,
+	0xa9, 0xab, 0x89, 0x7f, 0xb2, 0xee, 0xff, 0x37, 0x00, 0x8d, 0x72, 0xa9, 0xdb, 0xd6, 0x12, 0x00,
+	0x00,
 }
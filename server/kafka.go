@@ -0,0 +1,381 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+)
+
+// metadataTimeout is how long to wait for the metadata of the topic, in
+// milliseconds
+const metadataTimeout = 10000
+
+// kafkaTransport is a LogTransport backed by a Kafka topic. The topic and
+// the consumer group are both named after the cluster. The announcements of
+// the members and the workflow definitions are published to two more topics
+// with a single partition, that every member reads from the beginning.
+// Both are keyed so that they can be compacted.
+type kafkaTransport struct {
+	sync.Mutex
+	name      string
+	members   string
+	workflows string
+	brokers   string
+	// settings are passed through to the Kafka clients
+	settings map[string]string
+	// partitions is the number of partitions of the topic, read from the
+	// metadata on first use
+	partitions int
+
+	producer        *kafka.Producer
+	consumer        *kafka.Consumer
+	membersConsumer *kafka.Consumer
+
+	events     chan LogEvent
+	stopC      chan bool
+	shutdownWG sync.WaitGroup
+}
+
+// NewKafkaTransport creates a LogTransport for the cluster name, using
+// the Kafka brokers. The settings are passed through to the Kafka clients.
+func NewKafkaTransport(name string, brokers string, settings map[string]string) (LogTransport, error) {
+	p, err := kafka.NewProducer(kafkaConfig(settings, kafka.ConfigMap{"bootstrap.servers": brokers}))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &kafkaTransport{
+		name:      name,
+		members:   name + "-members",
+		workflows: name + "-workflows",
+		brokers:   brokers,
+		settings:  settings,
+		producer:  p,
+		events:    make(chan LogEvent),
+		stopC:     make(chan bool),
+	}
+
+	// delivery reports are sent to the per-message channel in Produce, so
+	// only errors and other events show up here.
+	t.shutdownWG.Add(1)
+	go func() {
+		defer t.shutdownWG.Done()
+
+		for e := range p.Events() {
+			fmt.Printf("Ignored event: %s\n", e)
+		}
+	}()
+
+	return t, nil
+}
+
+// Produce sends a message to the topic, in the partition of its key
+func (t *kafkaTransport) Produce(key []byte, value []byte) (*LogMessage, error) {
+	p, err := t.Partition(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.produce(t.name, p, key, value)
+}
+
+// produce sends a message to a partition of a topic, and waits for the
+// delivery report
+func (t *kafkaTransport) produce(topic string, partition int32, key []byte, value []byte) (*LogMessage, error) {
+	deliveryC := make(chan kafka.Event, 1)
+	err := t.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition},
+		Key:            key,
+		Value:          value,
+	}, deliveryC)
+	if err != nil {
+		return nil, err
+	}
+
+	m := (<-deliveryC).(*kafka.Message)
+	if m.TopicPartition.Error != nil {
+		return nil, m.TopicPartition.Error
+	}
+
+	return &LogMessage{
+		Partition: m.TopicPartition.Partition,
+		Offset:    int64(m.TopicPartition.Offset),
+		Key:       m.Key,
+		Value:     m.Value,
+		Timestamp: m.Timestamp,
+	}, nil
+}
+
+// Partition returns the partition of a message key. The partition is
+// chosen by the transport rather than by the producer, so that every member
+// can tell which partition a job belongs to.
+func (t *kafkaTransport) Partition(key []byte) (int32, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.partitions == 0 {
+		md, err := t.producer.GetMetadata(&t.name, false, metadataTimeout)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read metadata of %s: %v", t.name, err)
+		}
+
+		topic, ok := md.Topics[t.name]
+		if !ok || len(topic.Partitions) == 0 {
+			return 0, fmt.Errorf("topic %s has no partitions", t.name)
+		}
+		t.partitions = len(topic.Partitions)
+	}
+
+	return partitionOf(key, t.partitions), nil
+}
+
+// Announce publishes the announcement to the members topic
+func (t *kafkaTransport) Announce(member *Member) error {
+	data, err := proto.Marshal(member)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.produce(t.members, 0, []byte(member.Address), data)
+	return err
+}
+
+// PublishWorkflow publishes the definition to the workflows topic
+func (t *kafkaTransport) PublishWorkflow(def *WorkflowDefinition) error {
+	data, err := proto.Marshal(def)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.produce(t.workflows, 0, []byte(def.Name), data)
+	return err
+}
+
+// kafkaConfig returns the config of a Kafka client, with the settings that
+// are passed through and the settings the transport requires
+func kafkaConfig(settings map[string]string, required kafka.ConfigMap) *kafka.ConfigMap {
+	cm := kafka.ConfigMap{}
+	for k, v := range settings {
+		cm[k] = v
+	}
+	for k, v := range required {
+		cm[k] = v
+	}
+
+	return &cm
+}
+
+// Subscribe creates the consumer of the topic
+func (t *kafkaTransport) Subscribe() (<-chan LogEvent, error) {
+	fmt.Printf("creating Kafka consumer for %s, broker: %s\n", t.name, t.brokers)
+
+	c, err := kafka.NewConsumer(kafkaConfig(t.settings, kafka.ConfigMap{
+		"bootstrap.servers":               t.brokers,
+		"group.id":                        t.name,
+		"session.timeout.ms":              6000,
+		"go.events.channel.enable":        true,
+		"go.application.rebalance.enable": true,
+		// the WAL takes each assignment as the whole set of partitions of
+		// the member, which a cooperative strategy does not deliver
+		"partition.assignment.strategy": "range",
+		// offsets are committed after the messages are written to the WAL
+		"enable.auto.commit":   false,
+		"default.topic.config": kafka.ConfigMap{"auto.offset.reset": "earliest"},
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %v", err)
+	}
+
+	fmt.Printf("Created Consumer %v\n", c)
+
+	if err := c.SubscribeTopics([]string{t.name}, nil); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %v", t.name, err)
+	}
+
+	t.consumer = c
+
+	// the members and workflows topics are read from the beginning by
+	// every member, so the consumer is assigned their partitions rather
+	// than joining a group
+	mc, err := kafka.NewConsumer(kafkaConfig(t.settings, kafka.ConfigMap{
+		"bootstrap.servers":        t.brokers,
+		"group.id":                 t.members,
+		"go.events.channel.enable": true,
+		"enable.auto.commit":       false,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create members consumer: %v", err)
+	}
+
+	err = mc.Assign([]kafka.TopicPartition{
+		{Topic: &t.members, Partition: 0, Offset: kafka.OffsetBeginning},
+		{Topic: &t.workflows, Partition: 0, Offset: kafka.OffsetBeginning},
+	})
+	if err != nil {
+		mc.Close()
+		return nil, fmt.Errorf("failed to assign %s and %s: %v", t.members, t.workflows, err)
+	}
+
+	t.membersConsumer = mc
+
+	t.shutdownWG.Add(2)
+	go t.runConsumer()
+	go t.runMembers()
+
+	return t.events, nil
+}
+
+// runMembers translates the messages of the members topic to
+// announcements, and the messages of the workflows topic to definitions
+func (t *kafkaTransport) runMembers() {
+	defer t.shutdownWG.Done()
+
+	for {
+		var ev LogEvent
+
+		select {
+		case <-t.stopC:
+			return
+
+		case e := <-t.membersConsumer.Events():
+			m, ok := e.(*kafka.Message)
+			if !ok {
+				continue
+			}
+
+			var err error
+			if ev, err = t.metadataEvent(m); err != nil {
+				fmt.Printf("Ignored invalid announcement or workflow %s: %v\n", string(m.Key), err)
+				continue
+			}
+		}
+
+		select {
+		case <-t.stopC:
+			return
+		case t.events <- ev:
+		}
+	}
+}
+
+// metadataEvent decodes a message of the members or the workflows topic
+func (t *kafkaTransport) metadataEvent(m *kafka.Message) (LogEvent, error) {
+	if m.TopicPartition.Topic != nil && *m.TopicPartition.Topic == t.workflows {
+		def := &WorkflowDefinition{}
+		return def, proto.Unmarshal(m.Value, def)
+	}
+
+	member := &Member{}
+	return member, proto.Unmarshal(m.Value, member)
+}
+
+// runConsumer translates the consumer events to log events
+func (t *kafkaTransport) runConsumer() {
+	defer t.shutdownWG.Done()
+
+	for {
+		var ev LogEvent
+
+		select {
+		case <-t.stopC:
+			return
+
+		case e := <-t.consumer.Events():
+			switch e := e.(type) {
+			case kafka.AssignedPartitions:
+				ev = PartitionsAssigned{Partitions: partitionIDs(e.Partitions)}
+
+			case kafka.RevokedPartitions:
+				ev = PartitionsRevoked{Partitions: partitionIDs(e.Partitions)}
+
+			case *kafka.Message:
+				ev = &LogMessage{
+					Partition: e.TopicPartition.Partition,
+					Offset:    int64(e.TopicPartition.Offset),
+					Key:       e.Key,
+					Value:     e.Value,
+					Timestamp: e.Timestamp,
+				}
+
+			case kafka.Error:
+				ev = e
+
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-t.stopC:
+			return
+		case t.events <- ev:
+		}
+	}
+}
+
+// Assign starts consuming the partitions after the given offsets
+func (t *kafkaTransport) Assign(offsets map[int32]int64) error {
+	tps := make([]kafka.TopicPartition, 0, len(offsets))
+	for p, o := range offsets {
+		offset := kafka.OffsetBeginning
+		if o >= 0 {
+			offset = kafka.Offset(o + 1)
+		}
+		tps = append(tps, kafka.TopicPartition{Topic: &t.name, Partition: p, Offset: offset})
+	}
+
+	return t.consumer.Assign(tps)
+}
+
+// HighWatermark queries the brokers for the offset of the next message of
+// a partition
+func (t *kafkaTransport) HighWatermark(partition int32) (int64, error) {
+	_, high, err := t.consumer.QueryWatermarkOffsets(t.name, partition, metadataTimeout)
+	return high, err
+}
+
+// Unassign stops consuming all partitions
+func (t *kafkaTransport) Unassign() error {
+	return t.consumer.Unassign()
+}
+
+// Commit commits the offset of the next message to consume
+func (t *kafkaTransport) Commit(partition int32, offset int64) error {
+	_, err := t.consumer.CommitOffsets([]kafka.TopicPartition{
+		{Topic: &t.name, Partition: partition, Offset: kafka.Offset(offset + 1)},
+	})
+	return err
+}
+
+// Close closes the consumer and the producer
+func (t *kafkaTransport) Close() error {
+	close(t.stopC)
+
+	var err error
+	if t.consumer != nil {
+		err = t.consumer.Close()
+	}
+
+	if t.membersConsumer != nil {
+		t.membersConsumer.Close()
+	}
+
+	// closing the producer closes its event channel
+	t.producer.Close()
+	t.shutdownWG.Wait()
+
+	return err
+}
+
+// partitionIDs returns the partition numbers of topic partitions
+func partitionIDs(tps []kafka.TopicPartition) []int32 {
+	ids := make([]int32, 0, len(tps))
+	for _, tp := range tps {
+		ids = append(ids, tp.Partition)
+	}
+
+	return ids
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"sort"
+	"sync"
+)
+
+// Membership is the view of the cluster members and the partitions of the
+// log they own. It is built from the announcements that the members publish
+// when their partitions are assigned or revoked, in log order, so that the
+// last announcement of a partition wins.
+type Membership struct {
+	sync.RWMutex
+	// address is the address of this member
+	address string
+	// owners is the address of the owner of each partition
+	owners map[int32]string
+	// members is the set of partitions of each member, by address
+	members map[string][]int32
+}
+
+// NewMembership creates an empty membership view for the member at address
+func NewMembership(address string) *Membership {
+	return &Membership{
+		address: address,
+		owners:  make(map[int32]string),
+		members: make(map[string][]int32),
+	}
+}
+
+// Update applies the announcement of a member. The partitions it claims
+// are taken from their previous owners, and a member left without
+// partitions is dropped, so that a member that stopped or crashed drops out
+// once its partitions are assigned to the others.
+func (m *Membership) Update(member *Member) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, p := range m.members[member.Address] {
+		if m.owners[p] == member.Address {
+			delete(m.owners, p)
+		}
+	}
+	delete(m.members, member.Address)
+
+	for _, p := range member.Partitions {
+		if prev, ok := m.owners[p]; ok && prev != member.Address {
+			m.remove(prev, p)
+		}
+		m.owners[p] = member.Address
+	}
+
+	if len(member.Partitions) > 0 {
+		m.members[member.Address] = member.Partitions
+	}
+}
+
+// remove takes a partition from the partitions of a member. It is called
+// with the lock held.
+func (m *Membership) remove(address string, partition int32) {
+	var partitions []int32
+	for _, p := range m.members[address] {
+		if p != partition {
+			partitions = append(partitions, p)
+		}
+	}
+
+	if len(partitions) == 0 {
+		delete(m.members, address)
+		return
+	}
+	m.members[address] = partitions
+}
+
+// Owner returns the address of the member that owns a partition
+func (m *Membership) Owner(partition int32) (string, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	address, ok := m.owners[partition]
+	return address, ok
+}
+
+// Local tells whether an address is the address of this member
+func (m *Membership) Local(address string) bool {
+	return address == m.address
+}
+
+// Peers returns the addresses of the other members that own partitions
+func (m *Membership) Peers() []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	var peers []string
+	for address := range m.members {
+		if address != m.address {
+			peers = append(peers, address)
+		}
+	}
+	sort.Strings(peers)
+
+	return peers
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMembership(t *testing.T) {
+	t.Parallel()
+
+	m := NewMembership("a:1")
+	m.Update(&Member{Address: "a:1", Partitions: []int32{0, 1}})
+	m.Update(&Member{Address: "b:1", Partitions: []int32{1, 2}})
+
+	if owner, _ := m.Owner(1); owner != "b:1" {
+		t.Errorf("expected partition 1 to be owned by b:1, actual: %s", owner)
+	}
+
+	// a stale announcement does not take back a partition
+	m.Update(&Member{Address: "a:1", Partitions: []int32{0}})
+	if owner, _ := m.Owner(1); owner != "b:1" {
+		t.Errorf("expected partition 1 to be owned by b:1, actual: %s", owner)
+	}
+
+	m.Update(&Member{Address: "b:1"})
+	if _, ok := m.Owner(2); ok {
+		t.Errorf("expected partition 2 to have no owner")
+	}
+
+	if peers := m.Peers(); len(peers) != 0 {
+		t.Errorf("expected no peers, actual: %v", peers)
+	}
+
+	// a member whose partitions are all taken over drops out
+	m.Update(&Member{Address: "c:1", Partitions: []int32{2, 3}})
+	m.Update(&Member{Address: "a:1", Partitions: []int32{0, 1, 2, 3}})
+	if peers := m.Peers(); len(peers) != 0 {
+		t.Errorf("expected c:1 to drop out, actual: %v", peers)
+	}
+}
+
+func TestRouterRedirect(t *testing.T) {
+	t.Parallel()
+
+	transport := NewMemoryLog(1).Transport()
+	defer transport.Close()
+
+	m := NewMembership("a:1")
+	r := NewRouter(transport, m)
+	defer r.Close()
+
+	redirect := metadata.NewIncomingContext(context.Background(), metadata.Pairs(redirectHeader, "true"))
+
+	// jobs of the local member are served locally
+	m.Update(&Member{Address: "a:1", Partitions: []int32{0}})
+	if c, err := r.Route(redirect, "wf1", "a"); c != nil || err != nil {
+		t.Errorf("expected a local job, actual: %v, %v", c, err)
+	}
+
+	m.Update(&Member{Address: "b:1", Partitions: []int32{0}})
+	_, err := r.Route(redirect, "wf1", "a")
+	if s, _ := status.FromError(err); s.Code() != codes.FailedPrecondition {
+		t.Errorf("expected a redirect to b:1, actual: %v", err)
+	}
+
+	if c, err := r.Route(context.Background(), "wf1", "a"); c == nil || err != nil {
+		t.Errorf("expected the job to be forwarded, actual: %v", err)
+	}
+
+	// forwarded requests are not forwarded again
+	fwd := metadata.NewIncomingContext(context.Background(), metadata.Pairs(forwardedHeader, "true"))
+	if c, err := r.Route(fwd, "wf1", "a"); c != nil || err != nil {
+		t.Errorf("expected a forwarded job to be served locally, actual: %v, %v", c, err)
+	}
+}
@@ -0,0 +1,376 @@
+package server
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrTransportClosed is returned when a closed transport is used
+var ErrTransportClosed = errors.New("transport closed")
+
+var (
+	memoryLogsLock sync.Mutex
+	// memoryLogs are the in-process logs by cluster name, so that the
+	// servers of a cluster in one process share their log
+	memoryLogs = make(map[string]*MemoryLog)
+)
+
+// sharedMemoryTransport returns a new member of the in-process log of a
+// cluster. The log is created with a number of partitions by its first
+// member, and dropped when its last member is closed.
+func sharedMemoryTransport(name string, partitions int) LogTransport {
+	memoryLogsLock.Lock()
+	defer memoryLogsLock.Unlock()
+
+	l, ok := memoryLogs[name]
+	if !ok {
+		l = NewMemoryLog(partitions)
+		l.name = name
+		memoryLogs[name] = l
+	}
+	l.open++
+
+	return l.Transport()
+}
+
+// MemoryLog is an in-process partitioned log. It is shared by the members
+// of one consumer group, which makes it possible to run a single node or
+// several nodes in a test without a Kafka broker.
+type MemoryLog struct {
+	sync.Mutex
+	// name is the cluster name of a shared log, and open the number of
+	// its members that are not closed, guarded by memoryLogsLock
+	name string
+	open int
+
+	partitions [][]*LogMessage
+	members    []*memoryTransport
+	// announcements is the last announcement of each member, in the
+	// order they are published
+	announcements []*Member
+	// workflows are the published workflow definitions, in order
+	workflows []*WorkflowDefinition
+	// changedC is closed and replaced whenever messages are produced or
+	// the group is rebalanced, to wake up the consumers
+	changedC chan bool
+}
+
+// NewMemoryLog creates an in-process log with a number of partitions
+func NewMemoryLog(partitions int) *MemoryLog {
+	return &MemoryLog{
+		partitions: make([][]*LogMessage, partitions),
+		changedC:   make(chan bool),
+	}
+}
+
+// Transport returns a new member of the log
+func (l *MemoryLog) Transport() LogTransport {
+	return &memoryTransport{
+		log:      l,
+		events:   make(chan LogEvent),
+		assigned: make(map[int32]int64),
+		stopC:    make(chan bool),
+	}
+}
+
+// partition returns the partition of a message key
+func (l *MemoryLog) partition(key []byte) int32 {
+	return partitionOf(key, len(l.partitions))
+}
+
+// changed wakes up the consumers. It is called with the lock held.
+func (l *MemoryLog) changed() {
+	close(l.changedC)
+	l.changedC = make(chan bool)
+}
+
+// rebalance revokes all partitions, and assigns them again round robin
+// over the members. It is called with the lock held.
+func (l *MemoryLog) rebalance() {
+	for _, m := range l.members {
+		if len(m.owned) > 0 {
+			m.pending = append(m.pending, PartitionsRevoked{Partitions: m.owned})
+		}
+		m.owned = nil
+		m.assigned = make(map[int32]int64)
+	}
+
+	for i := range l.partitions {
+		if len(l.members) == 0 {
+			break
+		}
+		m := l.members[i%len(l.members)]
+		m.owned = append(m.owned, int32(i))
+	}
+
+	for _, m := range l.members {
+		if len(m.owned) > 0 {
+			m.pending = append(m.pending, PartitionsAssigned{Partitions: m.owned})
+		}
+	}
+
+	l.changed()
+}
+
+// memoryTransport is a member of a MemoryLog
+type memoryTransport struct {
+	log *MemoryLog
+
+	events chan LogEvent
+	// pending are the rebalance events not yet delivered
+	pending []LogEvent
+	// owned are the partitions assigned by the group
+	owned []int32
+	// assigned is the offset of the next message to deliver, for each
+	// partition that is consumed
+	assigned map[int32]int64
+	// next is the partition to look at first, so that all partitions
+	// are consumed
+	next int
+
+	subscribed bool
+	closed     bool
+	stopC      chan bool
+	shutdownWG sync.WaitGroup
+}
+
+// Produce appends a message to the partition of its key
+func (t *memoryTransport) Produce(key []byte, value []byte) (*LogMessage, error) {
+	l := t.log
+	l.Lock()
+	defer l.Unlock()
+
+	if t.closed {
+		return nil, ErrTransportClosed
+	}
+
+	p := l.partition(key)
+	m := &LogMessage{
+		Partition: p,
+		Offset:    int64(len(l.partitions[p])),
+		Key:       append([]byte(nil), key...),
+		Value:     append([]byte(nil), value...),
+		Timestamp: time.Now(),
+	}
+	l.partitions[p] = append(l.partitions[p], m)
+	l.changed()
+
+	r := *m
+	return &r, nil
+}
+
+// Partition returns the partition of a message key
+func (t *memoryTransport) Partition(key []byte) (int32, error) {
+	return t.log.partition(key), nil
+}
+
+// Announce delivers the announcement to all members of the group
+func (t *memoryTransport) Announce(member *Member) error {
+	l := t.log
+	l.Lock()
+	defer l.Unlock()
+
+	if t.closed {
+		return ErrTransportClosed
+	}
+
+	a := *member
+	for i, prev := range l.announcements {
+		if prev.Address == a.Address {
+			l.announcements = append(l.announcements[:i], l.announcements[i+1:]...)
+			break
+		}
+	}
+	l.announcements = append(l.announcements, &a)
+	for _, m := range l.members {
+		r := a
+		m.pending = append(m.pending, &r)
+	}
+	l.changed()
+
+	return nil
+}
+
+// PublishWorkflow delivers the definition to all members of the group
+func (t *memoryTransport) PublishWorkflow(def *WorkflowDefinition) error {
+	l := t.log
+	l.Lock()
+	defer l.Unlock()
+
+	if t.closed {
+		return ErrTransportClosed
+	}
+
+	d := *def
+	l.workflows = append(l.workflows, &d)
+	for _, m := range l.members {
+		r := d
+		m.pending = append(m.pending, &r)
+	}
+	l.changed()
+
+	return nil
+}
+
+// Subscribe joins the group, which rebalances the partitions
+func (t *memoryTransport) Subscribe() (<-chan LogEvent, error) {
+	l := t.log
+	l.Lock()
+	defer l.Unlock()
+
+	if t.closed {
+		return nil, ErrTransportClosed
+	}
+
+	if !t.subscribed {
+		t.subscribed = true
+		for _, a := range l.announcements {
+			r := *a
+			t.pending = append(t.pending, &r)
+		}
+		for _, d := range l.workflows {
+			r := *d
+			t.pending = append(t.pending, &r)
+		}
+		l.members = append(l.members, t)
+		l.rebalance()
+
+		t.shutdownWG.Add(1)
+		go t.run()
+	}
+
+	return t.events, nil
+}
+
+// run delivers the rebalance events and the messages of the assigned
+// partitions
+func (t *memoryTransport) run() {
+	defer t.shutdownWG.Done()
+
+	for {
+		t.log.Lock()
+		ev := t.nextEvent()
+		changedC := t.log.changedC
+		t.log.Unlock()
+
+		if ev == nil {
+			select {
+			case <-t.stopC:
+				return
+			case <-changedC:
+			}
+			continue
+		}
+
+		select {
+		case <-t.stopC:
+			return
+		case t.events <- ev:
+		}
+	}
+}
+
+// nextEvent returns the next event to deliver, or nil. It is called with
+// the lock held.
+func (t *memoryTransport) nextEvent() LogEvent {
+	if len(t.pending) > 0 {
+		ev := t.pending[0]
+		t.pending = t.pending[1:]
+		return ev
+	}
+
+	partitions := make([]int32, 0, len(t.assigned))
+	for p := range t.assigned {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	for i := range partitions {
+		p := partitions[(t.next+i)%len(partitions)]
+		offset := t.assigned[p]
+		if offset < int64(len(t.log.partitions[p])) {
+			t.assigned[p] = offset + 1
+			t.next = (t.next + i + 1) % len(partitions)
+
+			r := *t.log.partitions[p][offset]
+			return &r
+		}
+	}
+
+	return nil
+}
+
+// Assign starts consuming partitions after the given offsets
+func (t *memoryTransport) Assign(offsets map[int32]int64) error {
+	l := t.log
+	l.Lock()
+	defer l.Unlock()
+
+	for p, o := range offsets {
+		t.assigned[p] = o + 1
+	}
+	l.changed()
+
+	return nil
+}
+
+// HighWatermark returns the offset of the next message of a partition
+func (t *memoryTransport) HighWatermark(partition int32) (int64, error) {
+	l := t.log
+	l.Lock()
+	defer l.Unlock()
+
+	return int64(len(l.partitions[partition])), nil
+}
+
+// Unassign stops consuming all partitions
+func (t *memoryTransport) Unassign() error {
+	l := t.log
+	l.Lock()
+	defer l.Unlock()
+
+	t.assigned = make(map[int32]int64)
+	return nil
+}
+
+// Commit does nothing, since the members resume from the offsets of their
+// WAL given to Assign, and the in-memory log has no consumer lag to report
+func (t *memoryTransport) Commit(partition int32, offset int64) error {
+	return nil
+}
+
+// Close leaves the group, which rebalances the partitions to the other
+// members. A shared log is dropped with its last member.
+func (t *memoryTransport) Close() error {
+	l := t.log
+	l.Lock()
+	if t.closed {
+		l.Unlock()
+		return nil
+	}
+	t.closed = true
+
+	for i, m := range l.members {
+		if m == t {
+			l.members = append(l.members[:i], l.members[i+1:]...)
+			l.rebalance()
+			break
+		}
+	}
+	l.Unlock()
+
+	close(t.stopC)
+	t.shutdownWG.Wait()
+
+	if l.name != "" {
+		memoryLogsLock.Lock()
+		if l.open--; l.open == 0 && memoryLogs[l.name] == l {
+			delete(memoryLogs, l.name)
+		}
+		memoryLogsLock.Unlock()
+	}
+
+	return nil
+}
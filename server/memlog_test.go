@@ -0,0 +1,87 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+// nextLogEvent waits for the next event of a subscription
+func nextLogEvent(t *testing.T, events <-chan LogEvent) LogEvent {
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("no event from the log")
+		return nil
+	}
+}
+
+func TestMemoryLog(t *testing.T) {
+	t.Parallel()
+
+	l := NewMemoryLog(2)
+	t1 := l.Transport()
+	defer t1.Close()
+
+	m, err := t1.Produce([]byte("wf1:a"), []byte("1"))
+	if err != nil {
+		t.Fatalf("failed to produce: %v", err)
+	}
+
+	events, err := t1.Subscribe()
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	assigned, ok := nextLogEvent(t, events).(PartitionsAssigned)
+	if !ok || len(assigned.Partitions) != 2 {
+		t.Fatalf("expected 2 assigned partitions, actual: %v", assigned)
+	}
+	t1.Assign(map[int32]int64{0: -1, 1: -1})
+
+	r, ok := nextLogEvent(t, events).(*LogMessage)
+	if !ok || r.Partition != m.Partition || r.Offset != m.Offset || string(r.Value) != "1" {
+		t.Fatalf("expected message %v, actual: %v", m, r)
+	}
+	t1.Commit(r.Partition, r.Offset)
+
+	// a second member takes over one of the partitions
+	t2 := l.Transport()
+	defer t2.Close()
+
+	if _, err := t2.Subscribe(); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	if _, ok := nextLogEvent(t, events).(PartitionsRevoked); !ok {
+		t.Fatalf("expected partitions to be revoked")
+	}
+
+	assigned, ok = nextLogEvent(t, events).(PartitionsAssigned)
+	if !ok || len(assigned.Partitions) != 1 {
+		t.Fatalf("expected 1 assigned partition, actual: %v", assigned)
+	}
+}
+
+func TestSharedMemoryLog(t *testing.T) {
+	t.Parallel()
+
+	t1 := sharedMemoryTransport(t.Name(), 2)
+	t2 := sharedMemoryTransport(t.Name(), 2)
+	if t1.(*memoryTransport).log != t2.(*memoryTransport).log {
+		t.Fatalf("expected the members of a cluster to share the log")
+	}
+
+	if _, err := t1.Produce([]byte("wf1:a"), []byte("1")); err != nil {
+		t.Fatalf("failed to produce: %v", err)
+	}
+	t1.Close()
+	t2.Close()
+
+	// the log is dropped with its last member
+	t3 := sharedMemoryTransport(t.Name(), 2)
+	defer t3.Close()
+	if high, _ := t3.HighWatermark(t3.(*memoryTransport).log.partition([]byte("wf1:a"))); high != 0 {
+		t.Errorf("expected a new log, actual: %d messages", high)
+	}
+}
@@ -1,88 +1,945 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+const (
+	// expireInterval is how often polled jobs are checked for an expired
+	// visibility timeout
+	expireInterval = time.Second
+	// checkpointInterval is how often the live jobs are saved to the store,
+	// which bounds the number of WAL entries to replay on recovery
+	checkpointInterval = time.Minute
 )
 
+// ErrJobActive is returned when a job is added again before it is finished
+var ErrJobActive = errors.New("job is active")
+
 // MemStore stores the current live job queues. It also allows the consumer to
-// poll one job that is not being processed.
+// poll one job that is not being processed. The queues are scoped to the
+// partitions of the log that this node owns, so that a job is only served
+// by the node that consumes its partition.
 type MemStore struct {
 	sync.RWMutex
-	// checkpoint is the current key in the WAL
-	checkpoint string
+	// offsets is the offset of the last applied WAL entry of each owned
+	// partition
+	offsets map[int32]int64
+	// dirty is the set of partitions with WAL entries applied since their
+	// last checkpoint
+	dirty map[int32]bool
+	// applyLock serializes applying WAL entries and taking checkpoints
+	applyLock sync.Mutex
+	store     *Store
 	// size is the capacity of each queue
 	size int
-	// queues is a set of in-memory queues that is identified by workfow-state
-	// so that a client can
-	queues map[string]map[string]*Queue
-	// map from a job identified by {workflow}-{name}, to the current
-	// state of the job.
-	jobStateMap map[string]string
+	// timeout is the default visibility timeout of polled jobs
+	timeout time.Duration
+	// workflows provides the per-state settings of the queues
+	workflows *Workflows
+	// timers holds the delayed jobs until they are due
+	timers *Timers
+	// deadLetters holds the jobs that failed without retry
+	deadLetters *DeadLetters
+	// history records the events of the jobs
+	history *History
+	// records holds the current record of each job, to query the jobs
+	records *JobRecords
+	// owned is the set of partitions assigned to this node. A partition is
+	// true once its queues are rebuilt and its consumer caught up with the
+	// log, and polls are served from it.
+	owned map[int32]bool
+	// next is the partition to poll first, so that all partitions are
+	// served
+	next int
+	// queues is a set of in-memory queues for each partition, that is
+	// identified by workfow-state so that a client can
+	queues map[int32]map[string]map[string]*Queue
+	// map from a job identified by {workflow}-{name}, to the partition and
+	// the current state of the job.
+	jobStateMap map[string]jobState
+	// reserved is the partition and the state of the jobs admitted and
+	// not yet applied from the log
+	reserved map[string]jobState
+	// readyC is closed and replaced whenever jobs become ready to poll, to
+	// wake up the waiting polls
+	readyC chan bool
+
+	stopC      chan bool
+	shutdownWG sync.WaitGroup
+}
+
+// jobState is the partition and the current state of a job
+type jobState struct {
+	partition int32
+	state     string
 }
 
 // NewMemStore creats a new instance of MemStore
-func NewMemStore(size int) *MemStore {
+func NewMemStore(store *Store, size int, timeout time.Duration, workflows *Workflows) *MemStore {
 	return &MemStore{
+		store:       store,
 		size:        size,
-		queues:      make(map[string]map[string]*Queue),
-		jobStateMap: make(map[string]string),
+		timeout:     timeout,
+		workflows:   workflows,
+		timers:      NewTimers(store),
+		deadLetters: NewDeadLetters(store),
+		history:     NewHistory(store),
+		records:     NewJobRecords(store),
+		offsets:     make(map[int32]int64),
+		dirty:       make(map[int32]bool),
+		owned:       make(map[int32]bool),
+		queues:      make(map[int32]map[string]map[string]*Queue),
+		jobStateMap: make(map[string]jobState),
+		reserved:    make(map[string]jobState),
+		readyC:      make(chan bool),
+	}
+}
+
+// Assign takes ownership of partitions of the log. The queues of each
+// partition are rebuilt from its last checkpoint and the WAL entries written
+// after it. Polls are served from a partition once it is marked caught up.
+func (m *MemStore) Assign(partitions []int32) error {
+	for _, p := range partitions {
+		if err := m.recover(p); err != nil {
+			return fmt.Errorf("failed to recover partition %d: %v", p, err)
+		}
+	}
+
+	return nil
+}
+
+// recover rebuilds the queues of a partition
+func (m *MemStore) recover(partition int32) error {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	m.Lock()
+	if _, ok := m.owned[partition]; ok {
+		m.Unlock()
+		return nil
+	}
+	m.owned[partition] = false
+	m.queues[partition] = make(map[string]map[string]*Queue)
+	m.Unlock()
+
+	if err := m.timers.Load(partition); err != nil {
+		return err
+	}
+
+	checkpoint, snapshot, err := m.store.Checkpoint(partition)
+	if err != nil {
+		return err
+	}
+
+	jobs := make([]Job, 0, len(snapshot))
+	for k, data := range snapshot {
+		j := Job{}
+		if err := proto.Unmarshal(data, &j); err != nil {
+			return fmt.Errorf("invalid snapshot of job %s: %v", k, err)
+		}
+		jobs = append(jobs, j)
+	}
+
+	// offer the jobs in log order
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Offset < jobs[j].Offset })
+
+	for _, j := range jobs {
+		m.Offer(j.Workflow, j.State, j)
+	}
+	m.offsets[partition] = checkpoint
+
+	entries := 0
+	err = m.store.ScanWAL(partition, checkpoint, func(offset int64, v []byte) error {
+		j := Job{}
+		if err := proto.Unmarshal(v, &j); err != nil {
+			fmt.Printf("Skipped WAL entry [%d]@%d. Error: %s\n", partition, offset, err.Error())
+			return nil
+		}
+
+		j.Partition = partition
+		j.Offset = offset
+		m.apply(j, m.logTime(j))
+		entries++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Recovered %d jobs from checkpoint and %d WAL entries of partition %d\n", len(jobs), entries, partition)
+	return nil
+}
+
+// CaughtUp marks an owned partition as caught up with the log, so that its
+// jobs are served
+func (m *MemStore) CaughtUp(partition int32) {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.owned[partition]; ok {
+		m.owned[partition] = true
+		m.notify()
+	}
+}
+
+// Revoke gives up the ownership of partitions of the log. Their queues are
+// saved to a checkpoint and dropped, and their jobs are no longer served.
+func (m *MemStore) Revoke(partitions []int32) {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	for _, p := range partitions {
+		if err := m.checkpoint(p); err != nil {
+			fmt.Printf("Failed to save checkpoint of partition %d. Error: %s\n", p, err.Error())
+		}
+
+		m.Lock()
+		delete(m.owned, p)
+		delete(m.queues, p)
+		for k, js := range m.jobStateMap {
+			if js.partition == p {
+				delete(m.jobStateMap, k)
+			}
+		}
+		for k, js := range m.reserved {
+			if js.partition == p {
+				delete(m.reserved, k)
+			}
+		}
+		m.Unlock()
+
+		delete(m.offsets, p)
+		delete(m.dirty, p)
+		m.timers.Drop(p)
 	}
 }
 
 // Start will start the memstore service, which will read the WAL,
-// remove older entry when nessessary.
+// remove older entry when nessessary. It also redelivers the polled
+// jobs whose visibility timeout has expired, offers the delayed jobs
+// that are due, and saves checkpoints.
 func (m *MemStore) Start() {
+	m.stopC = make(chan bool)
+	m.shutdownWG.Add(1)
+	go m.run()
+}
+
+func (m *MemStore) run() {
+	defer m.shutdownWG.Done()
 
+	expireTicker := time.NewTicker(expireInterval)
+	defer expireTicker.Stop()
+
+	timerTicker := time.NewTicker(timerInterval)
+	defer timerTicker.Stop()
+
+	checkpointTicker := time.NewTicker(checkpointInterval)
+	defer checkpointTicker.Stop()
+
+	for {
+		select {
+		case <-m.stopC:
+			return
+
+		case now := <-expireTicker.C:
+			m.expire(now)
+
+		case now := <-timerTicker.C:
+			m.fire(now)
+
+		case <-checkpointTicker.C:
+			if err := m.Checkpoint(); err != nil {
+				fmt.Printf("Failed to save checkpoint. Error: %s\n", err.Error())
+			}
+		}
+	}
+}
+
+// Checkpoint saves the live jobs and the applied WAL offset of each owned
+// partition to the store. Partitions without a WAL entry applied since their
+// last checkpoint are skipped.
+func (m *MemStore) Checkpoint() error {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	for p := range m.dirty {
+		if err := m.checkpoint(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// Offer adds a new job to the mem store. If the job already exists with
-// a different state
+// checkpoint saves the live jobs of a partition. It is called with the apply
+// lock held.
+func (m *MemStore) checkpoint(partition int32) error {
+	if !m.dirty[partition] {
+		return nil
+	}
+
+	jobs := make(map[string][]byte)
+	for _, q := range m.partitionQueues(partition) {
+		for _, j := range q.Jobs() {
+			// leases do not survive a restart
+			j.LeaseToken = ""
+
+			data, err := proto.Marshal(&j)
+			if err != nil {
+				return err
+			}
+			jobs[jobKey(j.Workflow, j.Name)] = data
+		}
+	}
+
+	if err := m.store.SaveCheckpoint(partition, m.offsets[partition], jobs); err != nil {
+		return err
+	}
+
+	delete(m.dirty, partition)
+	return nil
+}
+
+// partitionQueues returns the queues of all workflow/state combinations of a
+// partition
+func (m *MemStore) partitionQueues(partition int32) []*Queue {
+	m.RLock()
+	defer m.RUnlock()
+
+	var queues []*Queue
+	for _, states := range m.queues[partition] {
+		for _, q := range states {
+			queues = append(queues, q)
+		}
+	}
+
+	return queues
+}
+
+// allQueues returns the queues of all partitions and workflow/state
+// combinations
+func (m *MemStore) allQueues() []*Queue {
+	m.RLock()
+	defer m.RUnlock()
+
+	var queues []*Queue
+	for _, workflows := range m.queues {
+		for _, states := range workflows {
+			for _, q := range states {
+				queues = append(queues, q)
+			}
+		}
+	}
+
+	return queues
+}
+
+// expire redelivers the expired jobs of all queues
+func (m *MemStore) expire(now time.Time) {
+	for _, q := range m.allQueues() {
+		if n := q.Expire(now); n > 0 {
+			fmt.Printf("redelivered %d expired jobs\n", n)
+			m.Lock()
+			m.notify()
+			m.Unlock()
+		}
+	}
+}
+
+// notify wakes up the waiting polls. It is called with the lock held.
+func (m *MemStore) notify() {
+	close(m.readyC)
+	m.readyC = make(chan bool)
+}
+
+// fire offers the delayed jobs of the rebuilt partitions that are due
+func (m *MemStore) fire(now time.Time) {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	m.RLock()
+	var partitions []int32
+	for p, ready := range m.owned {
+		if ready {
+			partitions = append(partitions, p)
+		}
+	}
+	m.RUnlock()
+
+	for _, p := range partitions {
+		jobs, err := m.timers.Due(p, now)
+		if err != nil {
+			fmt.Printf("Failed to read timers of partition %d. Error: %s\n", p, err.Error())
+		}
+
+		if len(jobs) == 0 {
+			continue
+		}
+
+		// a job stays in its timer until there is room in its queue
+		var offered []Job
+		for _, j := range jobs {
+			q := m.queueOf(j.Partition, j.Workflow, j.State)
+			if q == nil || q.Admit(j) != nil {
+				continue
+			}
+			m.Offer(j.Workflow, j.State, j)
+			q.Unreserve(j)
+			offered = append(offered, j)
+		}
+
+		if len(offered) == 0 {
+			continue
+		}
+
+		// the timers are deleted once the jobs are in a checkpoint, since
+		// the WAL entries of the jobs may be older than the checkpoint
+		m.dirty[p] = true
+		if err := m.checkpoint(p); err != nil {
+			fmt.Printf("Failed to save checkpoint of partition %d. Error: %s\n", p, err.Error())
+			continue
+		}
+
+		for _, j := range offered {
+			m.cancelTimer(j)
+		}
+	}
+}
+
+// Offer adds a new job to the queue of its partition. Jobs of partitions
+// that are not owned are ignored.
 func (m *MemStore) Offer(workflow string, state string, job Job) {
-	if _, ok := m.queues[workflow]; !ok {
-		m.Lock()
-		m.queues[workflow] = make(map[string]*Queue)
+	q := m.queueOf(job.Partition, workflow, state)
+	if q == nil {
+		return
+	}
+
+	q.Offer(job)
+
+	key := jobKey(workflow, job.Name)
+	m.Lock()
+	m.jobStateMap[key] = jobState{partition: job.Partition, state: state}
+	m.notify()
+	m.Unlock()
+}
+
+// queueOf returns the queue of a workflow state in a partition, which is
+// created on first use, or nil when the partition is not owned
+func (m *MemStore) queueOf(partition int32, workflow string, state string) *Queue {
+	if q := m.queue(partition, workflow, state); q != nil {
+		return q
+	}
+
+	timeout := m.timeout
+	if t := m.workflows.LeaseTimeout(workflow, state); t > 0 {
+		timeout = t
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	queues, ok := m.queues[partition]
+	if !ok {
+		return nil
+	}
+
+	if _, ok := queues[workflow]; !ok {
+		queues[workflow] = make(map[string]*Queue)
+	}
+
+	q := queues[workflow][state]
+	if q == nil {
+		q = NewQueue(m.size, timeout)
+		queues[workflow][state] = q
+	}
+
+	return q
+}
+
+// Admit reserves room for a job in the queue of its state in a partition,
+// before the job is published to the log. It returns ErrJobActive when a job
+// of the same name is not finished or is being added, and ErrQueueFull when
+// the queue is at capacity. The room is held until the job is applied from
+// the log, or released by Unreserve when the job is not published. Jobs
+// that come due after a delay wait in their timer until there is room.
+func (m *MemStore) Admit(partition int32, job Job) error {
+	return m.reserve(partition, job, true)
+}
+
+// Move reserves room for an active job in the queue of the state it moves
+// to, before its transition is published to the log. The room is held like
+// the room reserved by Admit.
+func (m *MemStore) Move(partition int32, job Job) error {
+	return m.reserve(partition, job, false)
+}
+
+// reserve reserves room for a job in the queue of its state, and checks
+// that a new job is finished
+func (m *MemStore) reserve(partition int32, job Job, added bool) error {
+	key := jobKey(job.Workflow, job.Name)
+
+	m.Lock()
+	if _, ok := m.reserved[key]; ok {
 		m.Unlock()
+		return ErrJobActive
 	}
+	m.reserved[key] = jobState{partition: partition, state: job.State}
+	m.Unlock()
 
-	if _, ok := m.queues[workflow][state]; !ok {
+	err := m.admit(partition, job, added)
+	if err != nil {
 		m.Lock()
-		m.queues[workflow][state] = NewQueue(m.size)
+		delete(m.reserved, key)
 		m.Unlock()
 	}
 
-	q := m.queues[workflow][state]
-	q.Offer(job)
+	return err
+}
+
+// admit checks that a new job is finished, and reserves room in its queue
+func (m *MemStore) admit(partition int32, job Job, added bool) error {
+	if added {
+		r, err := m.records.Get(job.Workflow, job.Name)
+		if err != nil {
+			return err
+		}
+		if r != nil && r.Status == Job_ACTIVE {
+			return ErrJobActive
+		}
+	}
+
+	if q := m.queueOf(partition, job.Workflow, job.State); q != nil {
+		return q.Admit(job)
+	}
+
+	return nil
+}
+
+// Unreserve releases the room reserved for a job by Admit
+func (m *MemStore) Unreserve(job Job) {
+	key := jobKey(job.Workflow, job.Name)
 
-	key := fmt.Sprintf("%s:%s", workflow, job.Name)
 	m.Lock()
-	m.jobStateMap[key] = state
+	js, ok := m.reserved[key]
+	delete(m.reserved, key)
+	q := m.queues[js.partition][job.Workflow][js.state]
 	m.Unlock()
+
+	if ok && q != nil {
+		q.Unreserve(job)
+	}
+}
+
+// Apply applies a job event read from the WAL entry at the partition and
+// offset of the job, with the time of its log message. Active jobs are
+// offered to the queue of their state, or held by a timer until their
+// not_before time, finished jobs are removed from the store. Events of
+// partitions that are not owned are ignored.
+func (m *MemStore) Apply(job Job, timestamp time.Time) {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	m.RLock()
+	_, ok := m.owned[job.Partition]
+	m.RUnlock()
+
+	if ok {
+		m.apply(job, timestamp)
+	}
 }
 
-// Poll returns a job if it exists in the store for a workflow/state combination
-func (m *MemStore) Poll(workflow string, state string) *Job {
-	if _, ok := m.queues[workflow]; !ok {
+// logTime returns the time of the log message of a failed job, which is
+// kept with the job in the WAL index. The time of the other events is not
+// needed to rebuild the queues.
+func (m *MemStore) logTime(job Job) time.Time {
+	var timestamp time.Time
+	if job.Status != Job_FAILED {
+		return timestamp
+	}
+
+	err := m.store.ScanJobWAL([]byte(jobKey(job.Workflow, job.Name)), func(partition int32, offset int64, ts time.Time, value []byte) error {
+		if partition == job.Partition && offset == job.Offset {
+			timestamp = ts
+		}
 		return nil
+	})
+	if err != nil {
+		fmt.Printf("Failed to read WAL index of job %s. Error: %s\n", jobKey(job.Workflow, job.Name), err.Error())
+	}
+
+	return timestamp
+}
+
+// apply applies a job event. It is called with the apply lock held.
+func (m *MemStore) apply(job Job, timestamp time.Time) {
+	m.offsets[job.Partition] = job.Offset
+	m.dirty[job.Partition] = true
+
+	// a priority update holds no room, the job keeps its place
+	if job.PriorityUpdate {
+		m.raise(job)
+		return
+	}
+
+	// the room reserved by Admit is released once the job is in its
+	// queue or held by a timer
+	defer m.Unreserve(job)
+
+	if err := m.records.Put(job); err != nil {
+		fmt.Printf("Failed to store record of job %s. Error: %s\n", jobKey(job.Workflow, job.Name), err.Error())
+	}
+
+	switch {
+	case job.Status == Job_COMPLETED:
+		m.cancelTimer(job)
+		m.Finish(job.Workflow, job.Name)
+
+	case job.Status == Job_FAILED:
+		m.cancelTimer(job)
+		m.Finish(job.Workflow, job.Name)
+		m.deadLetter(job, timestamp)
+
+	case job.Status == Job_CANCELLED:
+		// a polled job is released, and its worker learns of the
+		// cancellation on its next heartbeat
+		m.cancelTimer(job)
+		m.Finish(job.Workflow, job.Name)
+
+	case Delayed(job, time.Now()):
+		// the job leaves its queue until it is due
+		m.undeadLetter(job)
+		m.Finish(job.Workflow, job.Name)
+		if err := m.timers.Schedule(job); err != nil {
+			fmt.Printf("Failed to schedule job %s. Error: %s\n", jobKey(job.Workflow, job.Name), err.Error())
+		}
+
+	default:
+		m.undeadLetter(job)
+		m.cancelTimer(job)
+		m.Transition(job)
+	}
+}
+
+// raise applies a priority update to a job that is still active in the
+// state of the update. An update that was published while the job moved on
+// is ignored.
+func (m *MemStore) raise(job Job) {
+	key := jobKey(job.Workflow, job.Name)
+
+	r, err := m.records.Get(job.Workflow, job.Name)
+	if err != nil {
+		fmt.Printf("Failed to read record of job %s. Error: %s\n", key, err.Error())
+		return
+	}
+	if r == nil || r.Status != Job_ACTIVE || r.State != job.State || r.Priority >= job.Priority {
+		return
+	}
+
+	r.Priority = job.Priority
+	r.Partition = job.Partition
+	r.Offset = job.Offset
+	if err := m.records.Put(*r); err != nil {
+		fmt.Printf("Failed to store record of job %s. Error: %s\n", key, err.Error())
+	}
+
+	if Delayed(*r, time.Now()) {
+		if err := m.timers.Schedule(*r); err != nil {
+			fmt.Printf("Failed to schedule job %s. Error: %s\n", key, err.Error())
+		}
+		return
+	}
+
+	if q := m.queue(job.Partition, job.Workflow, job.State); q != nil {
+		q.Raise(job.Workflow, job.Name, job.Priority)
+	}
+}
+
+// Transition offers a job to the queue of its state. When the job is in
+// the queue of another state, waiting or polled, it is removed from that
+// queue first, so that it is never in both queues.
+func (m *MemStore) Transition(job Job) {
+	m.RLock()
+	js, ok := m.jobStateMap[jobKey(job.Workflow, job.Name)]
+	m.RUnlock()
+
+	if ok && js.state != job.State {
+		if q := m.queue(js.partition, job.Workflow, js.state); q != nil {
+			q.Remove(job)
+		}
+	}
+
+	m.Offer(job.Workflow, job.State, job)
+}
+
+// deadLetter adds a failed job to the dead-letter queue of its workflow
+func (m *MemStore) deadLetter(job Job, timestamp time.Time) {
+	data, err := proto.Marshal(&job)
+	if err == nil {
+		err = m.deadLetters.Put(&DeadLetter{
+			Workflow:  job.Workflow,
+			Name:      job.Name,
+			State:     job.State,
+			Error:     job.Error,
+			Payload:   data,
+			FailedAt:  timestamp.UnixNano(),
+			Partition: job.Partition,
+			Offset:    job.Offset,
+		})
+	}
+
+	if err != nil {
+		fmt.Printf("Failed to dead-letter job %s. Error: %s\n", jobKey(job.Workflow, job.Name), err.Error())
+	}
+}
+
+// undeadLetter removes a job that is active again from the dead-letter queue
+func (m *MemStore) undeadLetter(job Job) {
+	if err := m.deadLetters.Delete(job.Workflow, job.Name); err != nil {
+		fmt.Printf("Failed to remove job %s from the dead-letter queue. Error: %s\n", jobKey(job.Workflow, job.Name), err.Error())
+	}
+}
+
+// cancelTimer deletes the timer of a job, if it is delayed
+func (m *MemStore) cancelTimer(job Job) {
+	if err := m.timers.Cancel(job.Workflow, job.Name); err != nil {
+		fmt.Printf("Failed to cancel timer of job %s. Error: %s\n", jobKey(job.Workflow, job.Name), err.Error())
+	}
+}
+
+// Finish removes a job that is completed, failed or cancelled from the store
+func (m *MemStore) Finish(workflow string, name string) {
+	key := jobKey(workflow, name)
+
+	m.Lock()
+	js, ok := m.jobStateMap[key]
+	delete(m.jobStateMap, key)
+	m.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if q := m.queue(js.partition, workflow, js.state); q != nil {
+		q.Remove(Job{Workflow: workflow, Name: name})
 	}
+}
 
-	if _, ok := m.queues[workflow][state]; !ok {
+// jobQueue returns the queue of the current state of a job, or nil
+func (m *MemStore) jobQueue(workflow string, name string) *Queue {
+	m.RLock()
+	js, ok := m.jobStateMap[jobKey(workflow, name)]
+	m.RUnlock()
+
+	if !ok {
 		return nil
 	}
 
-	q := m.queues[workflow][state]
-	if q.size == 0 {
+	return m.queue(js.partition, workflow, js.state)
+}
+
+// Waiting returns a job that is waiting to be polled in its current state
+func (m *MemStore) Waiting(workflow string, name string) (Job, bool) {
+	q := m.jobQueue(workflow, name)
+	if q == nil {
+		return Job{}, false
+	}
+
+	return q.Waiting(workflow, name)
+}
+
+// Leased returns a job that is polled from its current state and not yet
+// completed or failed
+func (m *MemStore) Leased(workflow string, name string) (Job, bool) {
+	q := m.jobQueue(workflow, name)
+	if q == nil {
+		return Job{}, false
+	}
+
+	return q.Hidden(workflow, name)
+}
+
+// Lease returns a job that is polled from its current state, with the time
+// its lease expires
+func (m *MemStore) Lease(workflow string, name string) (Job, time.Time, bool) {
+	q := m.jobQueue(workflow, name)
+	if q == nil {
+		return Job{}, time.Time{}, false
+	}
+
+	return q.Lease(workflow, name)
+}
+
+// Owns tells whether a partition is owned by this node
+func (m *MemStore) Owns(partition int32) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, ok := m.owned[partition]
+	return ok
+}
+
+// Ready tells whether a partition is owned by this node and caught up with
+// the log
+func (m *MemStore) Ready(partition int32) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.owned[partition]
+}
+
+// Extend renews the lease of a polled job
+func (m *MemStore) Extend(workflow string, name string, token string, progress string) (Job, error) {
+	q := m.jobQueue(workflow, name)
+	if q == nil {
+		return Job{}, ErrLeaseExpired
+	}
+
+	return q.Extend(workflow, name, token, progress)
+}
+
+// Release removes a polled job from the queue of the given state, if it is
+// still leased with the token
+func (m *MemStore) Release(workflow string, state string, name string, token string) {
+	m.RLock()
+	js, ok := m.jobStateMap[jobKey(workflow, name)]
+	m.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	if q := m.queue(js.partition, workflow, state); q != nil {
+		q.Release(workflow, name, token)
+	}
+}
+
+// queue returns the queue of a workflow/state combination in a partition,
+// or nil
+func (m *MemStore) queue(partition int32, workflow string, state string) *Queue {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.queues[partition][workflow][state]
+}
+
+// Poll returns a job if it exists in the store for a workflow/state
+// combination, in any of the partitions that are rebuilt. The job with the
+// highest priority is returned, and partitions take turns on a tie. When
+// there is no job, Poll waits until one is offered or the context is done,
+// and returns nil. A context that is already done polls without waiting.
+func (m *MemStore) Poll(ctx context.Context, workflow string, state string) *Job {
+	for {
+		m.RLock()
+		readyC := m.readyC
+		m.RUnlock()
+
+		if j := m.tryPoll(workflow, state); j != nil {
+			return j
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-readyC:
+		}
+	}
+}
+
+// PollBatch returns up to max jobs of a workflow/state combination, in the
+// order Poll returns them. It waits like Poll for the first job only.
+func (m *MemStore) PollBatch(ctx context.Context, workflow string, state string, max int) []*Job {
+	j := m.Poll(ctx, workflow, state)
+	if j == nil {
 		return nil
 	}
 
-	j := q.Poll()
-	return &j
+	jobs := []*Job{j}
+	for len(jobs) < max {
+		j := m.tryPoll(workflow, state)
+		if j == nil {
+			break
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs
+}
+
+// tryPoll polls a job without waiting
+func (m *MemStore) tryPoll(workflow string, state string) *Job {
+	m.Lock()
+	partitions := make([]int32, 0, len(m.owned))
+	for p, ready := range m.owned {
+		if ready {
+			partitions = append(partitions, p)
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	var queues []*Queue
+	for _, p := range partitions {
+		if q := m.queues[p][workflow][state]; q != nil {
+			queues = append(queues, q)
+		}
+	}
+	next := m.next
+	m.next++
+	m.Unlock()
+
+	for len(queues) > 0 {
+		var top int
+		var priority int32
+		found := false
+		for i := range queues {
+			q := queues[(next+i)%len(queues)]
+			if p, ok := q.Priority(); ok && (!found || p > priority) {
+				top = (next + i) % len(queues)
+				priority = p
+				found = true
+			}
+		}
+
+		if !found {
+			return nil
+		}
+
+		// another poll may take the job in between, then the next queue
+		// is tried
+		if j, ok := queues[top].TryPoll(); ok {
+			return &j
+		}
+		queues = append(queues[:top], queues[top+1:]...)
+	}
+
+	return nil
 }
 
 // Stop stops the memstore service. It is called when the server is
 // gracefully shutdown.
 func (m *MemStore) Stop() {
-	m.checkpoint = ""
-	m.queues = make(map[string]map[string]*Queue)
-	m.jobStateMap = make(map[string]string)
+	if m.stopC != nil {
+		close(m.stopC)
+		m.shutdownWG.Wait()
+		m.stopC = nil
+	}
+
+	if err := m.Checkpoint(); err != nil {
+		fmt.Printf("Failed to save checkpoint. Error: %s\n", err.Error())
+	}
+
+	m.offsets = make(map[int32]int64)
+	m.dirty = make(map[int32]bool)
+	m.timers = NewTimers(m.store)
+	m.owned = make(map[int32]bool)
+	m.queues = make(map[int32]map[string]map[string]*Queue)
+	m.jobStateMap = make(map[string]jobState)
+	m.reserved = make(map[string]jobState)
 }
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// newTestStore opens a store in a directory that is removed after the test
+func newTestStore(t *testing.T) *Store {
+	store := NewStore(t.Name(), t.TempDir())
+	if err := store.Open(); err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+
+	return store
+}
+
+// assignTestPartition rebuilds partition 0, and serves it as if its
+// consumer caught up with the log
+func assignTestPartition(t *testing.T, m *MemStore) {
+	if err := m.Assign([]int32{0}); err != nil {
+		t.Fatalf("failed to assign partition: %v", err)
+	}
+	m.CaughtUp(0)
+}
+
+func appendTestJob(t *testing.T, store *Store, m *MemStore, offset int64, job Job) {
+	data, err := proto.Marshal(&job)
+	if err != nil {
+		t.Fatalf("failed to encode job: %v", err)
+	}
+
+	key := []byte(jobKey(job.Workflow, job.Name))
+	now := time.Now()
+	if err := store.AppendWAL(0, offset, key, now, data, nil); err != nil {
+		t.Fatalf("failed to append job: %v", err)
+	}
+
+	job.Offset = offset
+	m.Apply(job, now)
+}
+
+func TestMemStoreRecover(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1"})
+	appendTestJob(t, store, m, 1, Job{Workflow: "wf1", Name: "b", State: "s1"})
+
+	if err := m.Checkpoint(); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+
+	// entries after the checkpoint are replayed from the WAL
+	appendTestJob(t, store, m, 2, Job{Workflow: "wf1", Name: "a", State: "s2"})
+	appendTestJob(t, store, m, 3, Job{Workflow: "wf1", Name: "b", State: "s1", Status: Job_COMPLETED})
+
+	r := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, r)
+
+	if len(r.jobStateMap) != 1 || r.jobStateMap["wf1:a"].state != "s2" {
+		t.Errorf("expected wf1:a in state s2, actual: %v", r.jobStateMap)
+	}
+
+	j := r.tryPoll("wf1", "s2")
+	if j == nil || j.Name != "a" {
+		t.Errorf("expected to poll job a, actual: %v", j)
+	}
+}
+
+func TestMemStoreRevoke(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1"})
+
+	m.Revoke([]int32{0})
+	if j := m.tryPoll("wf1", "s1"); j != nil {
+		t.Errorf("expected no job of a revoked partition, actual: %v", j)
+	}
+
+	// entries of a partition that is not owned are not applied
+	appendTestJob(t, store, m, 1, Job{Workflow: "wf1", Name: "b", State: "s1"})
+	if len(m.jobStateMap) != 0 {
+		t.Errorf("expected no jobs, actual: %v", m.jobStateMap)
+	}
+
+	// the partition is rebuilt when it is assigned again
+	assignTestPartition(t, m)
+	if len(m.jobStateMap) != 2 {
+		t.Errorf("expected 2 jobs, actual: %v", m.jobStateMap)
+	}
+}
+
+func TestMemStoreAdmit(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 2, time.Minute, nil)
+	assignTestPartition(t, m)
+
+	// concurrent adds cannot exceed the capacity
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- m.Admit(0, Job{Workflow: "wf1", Name: fmt.Sprintf("j%d", i), State: "s1"})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	admitted := 0
+	for err := range errs {
+		if err == nil {
+			admitted++
+		} else if err != ErrQueueFull {
+			t.Errorf("expected the queue to be full, actual: %v", err)
+		}
+	}
+	if admitted != 2 {
+		t.Fatalf("expected 2 jobs to be admitted, actual: %d", admitted)
+	}
+
+	// a job is admitted once, and its room is released when it is not
+	// published
+	m = NewMemStore(store, 2, time.Minute, nil)
+	assignTestPartition(t, m)
+	if err := m.Admit(0, Job{Workflow: "wf1", Name: "a", State: "s1"}); err != nil {
+		t.Fatalf("failed to admit job a: %v", err)
+	}
+	if err := m.Admit(0, Job{Workflow: "wf1", Name: "a", State: "s2"}); err != ErrJobActive {
+		t.Errorf("expected job a to be admitted once, actual: %v", err)
+	}
+	if err := m.Admit(0, Job{Workflow: "wf1", Name: "b", State: "s1"}); err != nil {
+		t.Fatalf("failed to admit job b: %v", err)
+	}
+	if err := m.Admit(0, Job{Workflow: "wf1", Name: "c", State: "s1"}); err != ErrQueueFull {
+		t.Errorf("expected the queue to be full, actual: %v", err)
+	}
+
+	m.Unreserve(Job{Workflow: "wf1", Name: "b"})
+	if err := m.Admit(0, Job{Workflow: "wf1", Name: "c", State: "s1"}); err != nil {
+		t.Errorf("expected the room of job b to be released, actual: %v", err)
+	}
+
+	// the room of a job is taken by the job once it is applied
+	appendTestJob(t, store, m, 100, Job{Workflow: "wf1", Name: "a", State: "s1"})
+	if err := m.Admit(0, Job{Workflow: "wf1", Name: "a", State: "s1"}); err != ErrJobActive {
+		t.Errorf("expected active job a to be rejected, actual: %v", err)
+	}
+	if err := m.Admit(0, Job{Workflow: "wf1", Name: "d", State: "s1"}); err != ErrQueueFull {
+		t.Errorf("expected the queue to be full, actual: %v", err)
+	}
+}
+
+func TestMemStoreMove(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 1, time.Minute, nil)
+	assignTestPartition(t, m)
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1"})
+	appendTestJob(t, store, m, 1, Job{Workflow: "wf1", Name: "b", State: "s2"})
+
+	// an active job moves to a state that has room
+	if err := m.Move(0, Job{Workflow: "wf1", Name: "a", State: "s2"}); err != ErrQueueFull {
+		t.Errorf("expected s2 to be full, actual: %v", err)
+	}
+	if err := m.Move(0, Job{Workflow: "wf1", Name: "a", State: "s3"}); err != nil {
+		t.Fatalf("failed to move job a: %v", err)
+	}
+	if err := m.Move(0, Job{Workflow: "wf1", Name: "c", State: "s3"}); err != ErrQueueFull {
+		t.Errorf("expected the room of job a to fill s3, actual: %v", err)
+	}
+
+	// the room is taken by the job once its transition is applied
+	appendTestJob(t, store, m, 2, Job{Workflow: "wf1", Name: "a", State: "s3", PreviousState: "s1"})
+	if err := m.Move(0, Job{Workflow: "wf1", Name: "b", State: "s1"}); err != nil {
+		t.Errorf("expected the room of job a in s1 to be released, actual: %v", err)
+	}
+	if n := m.queue(0, "wf1", "s3").Size(); n != 1 {
+		t.Errorf("expected job a in s3, actual size: %d", n)
+	}
+}
+
+func TestMemStoreRaise(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1"})
+	appendTestJob(t, store, m, 1, Job{Workflow: "wf1", Name: "b", State: "s1", Data: "b"})
+
+	appendTestJob(t, store, m, 2, Job{Workflow: "wf1", Name: "b", State: "s1", Priority: 5, PriorityUpdate: true})
+	if j := m.tryPoll("wf1", "s1"); j == nil || j.Name != "b" || j.Data != "b" {
+		t.Errorf("expected job b with its data first, actual: %v", j)
+	}
+	if r, err := m.records.Get("wf1", "b"); err != nil || r.Priority != 5 || r.Data != "b" {
+		t.Errorf("expected the record of b to be raised, actual: %v, %v", r, err)
+	}
+
+	// an update of a job that moved on is ignored
+	appendTestJob(t, store, m, 3, Job{Workflow: "wf1", Name: "a", State: "s2", PreviousState: "s1"})
+	appendTestJob(t, store, m, 4, Job{Workflow: "wf1", Name: "a", State: "s1", Priority: 5, PriorityUpdate: true})
+	if j := m.tryPoll("wf1", "s1"); j != nil {
+		t.Errorf("expected no job in s1, actual: %v", j)
+	}
+	if r, err := m.records.Get("wf1", "a"); err != nil || r.State != "s2" || r.Priority != 0 {
+		t.Errorf("expected the record of a to be kept, actual: %v, %v", r, err)
+	}
+}
+
+func TestMemStoreDeadLetterTime(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1", Status: Job_FAILED})
+
+	dl, err := m.deadLetters.Get("wf1", "a")
+	if err != nil || dl == nil {
+		t.Fatalf("expected job a to be dead-lettered, actual: %v, %v", dl, err)
+	}
+
+	// the failure time is the time of the log message, also on recovery
+	time.Sleep(10 * time.Millisecond)
+	r := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, r)
+	if rdl, err := r.deadLetters.Get("wf1", "a"); err != nil || rdl.FailedAt != dl.FailedAt {
+		t.Errorf("expected the failure time %d to be kept, actual: %v, %v", dl.FailedAt, rdl, err)
+	}
+}
+
+func TestMemStoreLongPoll(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+
+	// a context that is done polls without waiting
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if j := m.Poll(done, "wf1", "s1"); j != nil {
+		t.Errorf("expected no job, actual: %v", j)
+	}
+
+	// the first queue of a state is created by the offer
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.Offer("wf1", "s1", Job{Workflow: "wf1", Name: "a", State: "s1"})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if j := m.Poll(ctx, "wf1", "s1"); j == nil || j.Name != "a" {
+		t.Errorf("expected job a, actual: %v", j)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the poll to be woken by the offer")
+	}
+}
+
+func TestMemStoreTransition(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1"})
+	appendTestJob(t, store, m, 1, Job{Workflow: "wf1", Name: "b", State: "s1"})
+
+	// a is polled and b is waiting when they move on
+	if j := m.tryPoll("wf1", "s1"); j == nil || j.Name != "a" {
+		t.Fatalf("expected to poll job a, actual: %v", j)
+	}
+	appendTestJob(t, store, m, 2, Job{Workflow: "wf1", Name: "a", State: "s2", PreviousState: "s1"})
+	appendTestJob(t, store, m, 3, Job{Workflow: "wf1", Name: "b", State: "s2", PreviousState: "s1"})
+
+	if jobs := m.queue(0, "wf1", "s1").Jobs(); len(jobs) != 0 {
+		t.Errorf("expected no job left in s1, actual: %v", jobs)
+	}
+	if _, ok := m.Leased("wf1", "a"); ok {
+		t.Errorf("expected the lease of a in s1 to be dropped")
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if j := m.tryPoll("wf1", "s2"); j == nil || j.Name != name {
+			t.Errorf("expected to poll job %s in s2, actual: %v", name, j)
+		}
+	}
+}
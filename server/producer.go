@@ -1,59 +1,24 @@
 package server
 
 import (
-	"fmt"
-
-	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/golang/protobuf/proto"
 )
 
 // Producer can send jobs to the engine
 type Producer struct {
-	name    string
-	brokers string
-	doneC   chan bool
-
-	producer *kafka.Producer
+	transport LogTransport
 }
 
-func newProducer(name string, brokers string) *Producer {
-	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers})
-	if err != nil {
-		return nil
-	}
-
-	producer := &Producer{
-		name:     name,
-		brokers:  brokers,
-		producer: p,
-		doneC:    make(chan bool),
+func newProducer(transport LogTransport) *Producer {
+	return &Producer{
+		transport: transport,
 	}
-
-	go func() {
-		defer close(producer.doneC)
-
-		for e := range p.Events() {
-			switch ev := e.(type) {
-			case *kafka.Message:
-				m := ev
-				if m.TopicPartition.Error != nil {
-					fmt.Printf("Delivery failed: %v\n", m.TopicPartition.Error)
-				} else {
-					fmt.Printf("Delivered message to topic %s [%d] at offset %v\n",
-						*m.TopicPartition.Topic, m.TopicPartition.Partition, m.TopicPartition.Offset)
-				}
-				return
-
-			default:
-				fmt.Printf("Ignored event: %s\n", ev)
-			}
-		}
-	}()
-
-	return producer
 }
 
-// Produce sends a job to the workflow engine
+// Produce sends a job to the workflow engine. The job is keyed by
+// {workflow}:{name} so that all events of a job land in the same partition.
+// It blocks until the log accepts the message, and sets the partition and
+// offset assigned to the job.
 func (p *Producer) Produce(job *Job) error {
 
 	data, err := proto.Marshal(job)
@@ -61,14 +26,19 @@ func (p *Producer) Produce(job *Job) error {
 		return err
 	}
 
-	p.producer.ProduceChannel() <- &kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &p.name, Partition: kafka.PartitionAny},
-		Value:          data,
+	m, err := p.transport.Produce([]byte(jobKey(job.GetWorkflow(), job.GetName())), data)
+	if err != nil {
+		return err
 	}
 
+	job.Partition = m.Partition
+	job.Offset = m.Offset
+
 	return nil
 }
 
-func (p *Producer) Close() {
-	p.producer.Close()
+// PublishWorkflow sends a workflow definition to every member of the
+// cluster
+func (p *Producer) PublishWorkflow(def *WorkflowDefinition) error {
+	return p.transport.PublishWorkflow(def)
 }
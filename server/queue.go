@@ -1,119 +1,504 @@
 package server
 
 import (
+	"container/heap"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrLeaseExpired is returned when a job is no longer hidden because
+	// it was released or its visibility timeout expired
+	ErrLeaseExpired = errors.New("lease expired")
+	// ErrLeaseReassigned is returned when a job was polled again by
+	// another worker after the lease expired
+	ErrLeaseReassigned = errors.New("lease reassigned")
+	// ErrQueueFull is returned when a new job is added to a queue that
+	// holds as many waiting jobs as its capacity
+	ErrQueueFull = errors.New("queue full")
 )
 
-// Queue holds a dedup job queue for a specific workflow/state
+// Queue holds a dedup job queue for a specific workflow/state. Jobs with a
+// higher priority are served first, and jobs with the same priority are
+// served in the order they are offered.
 type Queue struct {
 	sync.RWMutex
 
-	size        int
-	jobMap      map[string]Job
+	// capacity is the number of waiting and reserved jobs that Admit
+	// accepts
+	capacity int
+	// reserved are the jobs admitted and not yet offered from the log
+	reserved map[string]bool
+	// timeout is how long a polled job stays hidden before it is
+	// redelivered
+	timeout time.Duration
+	jobMap  map[string]Job
+	// hiddenJobAt is when a job was polled or its lease last extended
 	hiddenJobAt map[string]time.Time
-	jobC        chan string
-	hiddenJobC  chan string
+	// hiddenJobMap holds the jobs that are polled but not yet released
+	hiddenJobMap map[string]Job
+	// items orders the keys of the waiting jobs, and itemMap finds the
+	// item of a key
+	items   queueHeap
+	itemMap map[string]*queueItem
+	// tail is the sequence of the last offered job, and head the sequence
+	// of the last redelivered job, which is served before the offered ones
+	tail int64
+	head int64
+	// readyC is closed and replaced whenever a job is offered, to wake up
+	// the waiting polls
+	readyC chan bool
+}
+
+// queueItem is a waiting job in the heap of a queue
+type queueItem struct {
+	key      string
+	priority int32
+	seq      int64
+	index    int
+}
+
+// queueHeap is a heap of waiting jobs, by priority and then sequence
+type queueHeap []*queueItem
+
+func (h queueHeap) Len() int { return len(h) }
+
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *queueHeap) Push(x interface{}) {
+	item := x.(*queueItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *queueHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
 }
 
 // NewQueue creates a new Queue with dedup
-func NewQueue(size int) *Queue {
+func NewQueue(size int, timeout time.Duration) *Queue {
 	q := Queue{
-		jobMap:      make(map[string]Job),
-		jobC:        make(chan string, size),
-		hiddenJobAt: make(map[string]time.Time),
-		hiddenJobC:  make(chan string, size),
+		capacity:     size,
+		timeout:      timeout,
+		jobMap:       make(map[string]Job),
+		hiddenJobAt:  make(map[string]time.Time),
+		hiddenJobMap: make(map[string]Job),
+		itemMap:      make(map[string]*queueItem),
+		reserved:     make(map[string]bool),
+		readyC:       make(chan bool),
 	}
 
 	return &q
 }
 
+// jobKey returns the key {workflow}:{name} that identifies a job
+func jobKey(workflow string, name string) string {
+	return fmt.Sprintf("%s:%s", workflow, name)
+}
+
+// Admit reserves room for a job in the queue before it is published to
+// the log, so that concurrent adds cannot exceed the capacity. It returns
+// ErrQueueFull when the waiting and reserved jobs fill the queue and the job
+// is not already waiting in it. The room is released by Unreserve.
+func (q *Queue) Admit(job Job) error {
+	k := jobKey(job.GetWorkflow(), job.GetName())
+
+	q.Lock()
+	defer q.Unlock()
+
+	if _, ok := q.itemMap[k]; ok || q.reserved[k] {
+		return nil
+	}
+
+	if len(q.items)+len(q.reserved) >= q.capacity {
+		return ErrQueueFull
+	}
+	q.reserved[k] = true
+
+	return nil
+}
+
+// Unreserve releases the room reserved for a job by Admit
+func (q *Queue) Unreserve(job Job) {
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.reserved, jobKey(job.GetWorkflow(), job.GetName()))
+}
+
 // Offer a new job to the dedup job queue. Each
 // job is identified by a unique key of the format
 // {workflow}:{job}, so that the same job will be
-// send to the same server instance.
+// send to the same server instance. A job that is
+// offered again keeps its place in the queue, unless
+// its priority is raised. A polled job is only offered
+// again by the event that ends its lease, which carries
+// its lease token, so that a duplicate event does not
+// hand the job to a second worker. Offer never blocks:
+// the jobs are already in the log, so the capacity is
+// enforced by Admit before they are published.
 func (q *Queue) Offer(job Job) {
-	k := fmt.Sprintf("%s:%s", job.GetWorkflow(), job.GetName())
+	k := jobKey(job.GetWorkflow(), job.GetName())
 
 	q.Lock()
 	defer q.Unlock()
 
-	if _, ok := q.jobMap[k]; !ok {
-		q.jobC <- k
-		q.size++
+	if h, ok := q.hiddenJobMap[k]; ok {
+		if job.LeaseToken == "" || job.LeaseToken != h.LeaseToken {
+			return
+		}
+		delete(q.hiddenJobAt, k)
+		delete(q.hiddenJobMap, k)
+	}
+	job.LeaseToken = ""
+
+	if item, ok := q.itemMap[k]; ok {
+		if job.Priority > item.priority {
+			item.priority = job.Priority
+			heap.Fix(&q.items, item.index)
+		}
+	} else {
+		q.tail++
+		q.push(&queueItem{key: k, priority: job.Priority, seq: q.tail})
 	}
 	q.jobMap[k] = job
 }
 
-// Poll returns a job and hide it from the queue.
-func (q *Queue) Poll() Job {
-	k := <-q.jobC
+// push adds an item to the heap. It is called with the lock held.
+func (q *Queue) push(item *queueItem) {
+	heap.Push(&q.items, item)
+	q.itemMap[item.key] = item
 
-	// find the first job that is not deleted
+	close(q.readyC)
+	q.readyC = make(chan bool)
+}
+
+// remove removes the item of a key from the heap. It is called with the
+// lock held.
+func (q *Queue) remove(k string) {
+	if item, ok := q.itemMap[k]; ok {
+		heap.Remove(&q.items, item.index)
+		delete(q.itemMap, k)
+	}
+}
+
+// newLeaseToken returns a random token identifying a lease
+func newLeaseToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return fmt.Sprintf("%x", b)
+}
+
+// Poll returns a job and hide it from the queue. The job is redelivered
+// if it is not released before the visibility timeout. The returned job
+// carries a new lease token. When the queue is empty, Poll waits until a
+// job is offered or the context is done, and returns the error of the
+// context.
+func (q *Queue) Poll(ctx context.Context) (Job, error) {
+	return q.wait(ctx, q.TryPoll)
+}
+
+// TryPoll polls a job without waiting. It returns false if the queue is
+// empty.
+func (q *Queue) TryPoll() (Job, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.items) == 0 {
+		return Job{}, false
+	}
+
+	k := q.items[0].key
+	q.remove(k)
+
+	r := q.jobMap[k]
+	r.LeaseToken = newLeaseToken()
+	delete(q.jobMap, k)
+	q.hiddenJobAt[k] = time.Now()
+	q.hiddenJobMap[k] = r
+
+	return r, true
+}
+
+// wait calls try until it returns a job, waiting for an offer between the
+// calls, or until the context is done
+func (q *Queue) wait(ctx context.Context, try func() (Job, bool)) (Job, error) {
 	for {
+		// the channel is taken before trying, so that an offer in
+		// between is not missed
 		q.RLock()
-		_, ok := q.jobMap[k]
+		readyC := q.readyC
 		q.RUnlock()
 
-		if !ok {
-			k = <-q.jobC
-		} else {
-			break
+		if j, ok := try(); ok {
+			return j, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Job{}, ctx.Err()
+		case <-readyC:
 		}
 	}
+}
 
-	q.hiddenJobC <- k
-	q.hiddenJobAt[k] = time.Now()
+// Expire returns the polled jobs whose visibility timeout has passed to
+// the head of their priority in the queue, with their attempt counter
+// incremented. When the queue is full, an expired job loses its lease and
+// stays hidden until there is room. It returns the number of redelivered
+// jobs.
+func (q *Queue) Expire(now time.Time) int {
+	q.Lock()
+	defer q.Unlock()
+
+	var keys []string
+	for k, at := range q.hiddenJobAt {
+		if now.Sub(at) >= q.timeout {
+			keys = append(keys, k)
+		}
+	}
+
+	// the job that was polled first is served first
+	sort.Slice(keys, func(i, j int) bool {
+		return q.hiddenJobAt[keys[i]].Before(q.hiddenJobAt[keys[j]])
+	})
+
+	q.head -= int64(len(keys))
+	redelivered := 0
+	for i, k := range keys {
+		j := q.hiddenJobMap[k]
+
+		// the job was offered again while it was hidden, and is already
+		// waiting in the queue
+		if _, ok := q.jobMap[k]; ok {
+			delete(q.hiddenJobAt, k)
+			delete(q.hiddenJobMap, k)
+			continue
+		}
+
+		if len(q.items)+len(q.reserved) >= q.capacity {
+			j.LeaseToken = ""
+			q.hiddenJobMap[k] = j
+			continue
+		}
+		delete(q.hiddenJobAt, k)
+		delete(q.hiddenJobMap, k)
+
+		j.Attempt++
+		j.LeaseToken = ""
+		q.jobMap[k] = j
+		q.push(&queueItem{key: k, priority: j.Priority, seq: q.head + int64(i)})
+		redelivered++
+	}
+
+	return redelivered
+}
+
+// Jobs returns all jobs in the queue, including the polled jobs that are
+// not yet released
+func (q *Queue) Jobs() []Job {
+	q.RLock()
+	defer q.RUnlock()
+
+	jobs := make([]Job, 0, len(q.jobMap)+len(q.hiddenJobMap))
+	for _, j := range q.jobMap {
+		jobs = append(jobs, j)
+	}
+	for _, j := range q.hiddenJobMap {
+		jobs = append(jobs, j)
+	}
+
+	return jobs
+}
+
+// Waiting returns a job that is waiting in the queue to be polled
+func (q *Queue) Waiting(workflow string, name string) (Job, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	j, ok := q.jobMap[jobKey(workflow, name)]
+	return j, ok
+}
+
+// Raise raises the priority of a job that is waiting or polled, so that the
+// job moves ahead of the jobs of a lower priority. The priority of a job is
+// never lowered.
+func (q *Queue) Raise(workflow string, name string, priority int32) {
+	k := jobKey(workflow, name)
 
 	q.Lock()
-	r := q.jobMap[k]
-	delete(q.jobMap, k)
-	q.Unlock()
+	defer q.Unlock()
 
-	return r
+	if j, ok := q.jobMap[k]; ok && priority > j.Priority {
+		j.Priority = priority
+		q.jobMap[k] = j
+	}
+	if item, ok := q.itemMap[k]; ok && priority > item.priority {
+		item.priority = priority
+		heap.Fix(&q.items, item.index)
+	}
+	if j, ok := q.hiddenJobMap[k]; ok && priority > j.Priority {
+		j.Priority = priority
+		q.hiddenJobMap[k] = j
+	}
 }
 
-// Remove removes the job from the queue
-func (q *Queue) Remove(job Job) {
-	k := fmt.Sprintf("%s:%s", job.GetWorkflow(), job.GetName())
+// Hidden returns a job that was polled and not yet released
+func (q *Queue) Hidden(workflow string, name string) (Job, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	j, ok := q.hiddenJobMap[jobKey(workflow, name)]
+	return j, ok
+}
+
+// Lease returns a polled job that is not yet released, with the time its
+// visibility timeout expires
+func (q *Queue) Lease(workflow string, name string) (Job, time.Time, bool) {
+	k := jobKey(workflow, name)
+
+	q.RLock()
+	defer q.RUnlock()
+
+	j, ok := q.hiddenJobMap[k]
+	if !ok {
+		return Job{}, time.Time{}, false
+	}
+
+	return j, q.hiddenJobAt[k].Add(q.timeout), true
+}
+
+// Extend renews the lease of a polled job for another visibility timeout,
+// and stores the progress with the job when it is set.
+func (q *Queue) Extend(workflow string, name string, token string, progress string) (Job, error) {
+	k := jobKey(workflow, name)
 
 	q.Lock()
 	defer q.Unlock()
 
-	delete(q.jobMap, k)
+	j, ok := q.hiddenJobMap[k]
+	if !ok || j.LeaseToken == "" {
+		return Job{}, ErrLeaseExpired
+	}
+
+	if j.LeaseToken != token {
+		return Job{}, ErrLeaseReassigned
+	}
+
+	if progress != "" {
+		j.Progress = progress
+		q.hiddenJobMap[k] = j
+	}
+	q.hiddenJobAt[k] = time.Now()
+
+	return j, nil
 }
 
-// Peak peaks a job without removing it from the queue
-func (q *Queue) Peak() Job {
-	k := <-q.jobC
-	q.jobC <- k
+// Release removes a polled job from the queue once it is processed, if it
+// is still leased with the token. A job that was polled again by another
+// worker keeps its new lease.
+func (q *Queue) Release(workflow string, name string, token string) {
+	k := jobKey(workflow, name)
 
 	q.Lock()
-	r := q.jobMap[k]
-	q.Unlock()
+	defer q.Unlock()
 
-	return r
+	if j, ok := q.hiddenJobMap[k]; ok && j.LeaseToken == token {
+		delete(q.hiddenJobAt, k)
+		delete(q.hiddenJobMap, k)
+	}
 }
 
-// PeakNext peaks a job without removing it from the queue
-func (q *Queue) PeakNext() Job {
-	k := <-q.jobC
-	q.jobC <- k
+// Remove removes the job from the queue, whether it is waiting or polled
+func (q *Queue) Remove(job Job) {
+	k := jobKey(job.GetWorkflow(), job.GetName())
 
 	q.Lock()
-	r := q.jobMap[k]
+	defer q.Unlock()
+
 	delete(q.jobMap, k)
-	q.Unlock()
+	q.remove(k)
+	delete(q.hiddenJobAt, k)
+	delete(q.hiddenJobMap, k)
+}
+
+// Peak peaks a job without removing it from the queue. It waits like Poll
+// when the queue is empty.
+func (q *Queue) Peak(ctx context.Context) (Job, error) {
+	return q.wait(ctx, func() (Job, bool) {
+		q.RLock()
+		defer q.RUnlock()
+
+		if len(q.items) == 0 {
+			return Job{}, false
+		}
+
+		return q.jobMap[q.items[0].key], true
+	})
+}
+
+// PeakNext takes the next job out of the queue without hiding it. It waits
+// like Poll when the queue is empty.
+func (q *Queue) PeakNext(ctx context.Context) (Job, error) {
+	return q.wait(ctx, func() (Job, bool) {
+		q.Lock()
+		defer q.Unlock()
+
+		if len(q.items) == 0 {
+			return Job{}, false
+		}
+
+		k := q.items[0].key
+		q.remove(k)
+
+		r := q.jobMap[k]
+		delete(q.jobMap, k)
+
+		return r, true
+	})
+}
 
-	return r
+// Priority returns the priority of the next job, and false if no job is
+// waiting
+func (q *Queue) Priority() (int32, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if len(q.items) == 0 {
+		return 0, false
+	}
 
+	return q.items[0].priority, true
 }
 
-// Size returns the current size of the queue
+// Size returns the number of jobs waiting in the queue, which excludes the
+// polled jobs
 func (q *Queue) Size() int {
 	q.RLock()
 	defer q.RUnlock()
 
-	return q.size
+	return len(q.items)
 }
@@ -3,12 +3,25 @@ package server
 import (
 	"strconv"
 	"testing"
+	"time"
+
+	"golang.org/x/net/context"
 )
 
+// pollTestQueue polls a job that is expected to be in the queue
+func pollTestQueue(t *testing.T, q *Queue) Job {
+	j, ok := q.TryPoll()
+	if !ok {
+		t.Fatalf("expected a job in the queue")
+	}
+
+	return j
+}
+
 func TestQueue(t *testing.T) {
 	t.Parallel()
 
-	q := NewQueue(100)
+	q := NewQueue(100, time.Minute)
 
 	for i := 1; i <= 10; i++ {
 		q.Offer(Job{
@@ -28,7 +41,7 @@ func TestQueue(t *testing.T) {
 	}
 
 	for i := 1; i <= 10; i++ {
-		j := q.Poll()
+		j := pollTestQueue(t, q)
 
 		if j.Name != strconv.Itoa(i) {
 			t.Errorf("expected ID %d, actual: %s", i, j.Name)
@@ -39,7 +52,7 @@ func TestQueue(t *testing.T) {
 func TestQueueDedup(t *testing.T) {
 	t.Parallel()
 
-	q := NewQueue(100)
+	q := NewQueue(100, time.Minute)
 
 	for i := 1; i <= 10; i++ {
 		q.Offer(Job{
@@ -49,7 +62,299 @@ func TestQueueDedup(t *testing.T) {
 		})
 	}
 
-	if q.size != 1 {
-		t.Errorf("expected queue size: 1, actual: %d", q.size)
+	if q.Size() != 1 {
+		t.Errorf("expected queue size: 1, actual: %d", q.Size())
+	}
+}
+
+func TestQueueRelease(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+	q.Offer(Job{
+		Workflow: "wf1",
+		Name:     "aaa",
+		Data:     "aaa",
+	})
+
+	j := pollTestQueue(t, q)
+	if _, ok := q.Hidden(j.Workflow, j.Name); !ok {
+		t.Errorf("expected job %s to be hidden after poll", j.Name)
+	}
+
+	// a stale lease does not release the job
+	q.Release(j.Workflow, j.Name, "stale")
+	if _, ok := q.Hidden(j.Workflow, j.Name); !ok {
+		t.Errorf("expected job %s to stay hidden", j.Name)
+	}
+
+	q.Release(j.Workflow, j.Name, j.LeaseToken)
+	if _, ok := q.Hidden(j.Workflow, j.Name); ok {
+		t.Errorf("expected job %s to be released", j.Name)
+	}
+}
+
+func TestQueueExpire(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+	for i := 1; i <= 2; i++ {
+		q.Offer(Job{
+			Workflow: "wf1",
+			Name:     strconv.Itoa(i),
+		})
+	}
+
+	j := pollTestQueue(t, q)
+	if n := q.Expire(time.Now()); n != 0 {
+		t.Errorf("expected no expired job, actual: %d", n)
+	}
+
+	if n := q.Expire(time.Now().Add(time.Minute)); n != 1 {
+		t.Errorf("expected 1 expired job, actual: %d", n)
+	}
+
+	// the expired job goes back to the head of the queue
+	r := pollTestQueue(t, q)
+	if r.Name != j.Name {
+		t.Errorf("expected job %s to be redelivered, actual: %s", j.Name, r.Name)
+	}
+
+	if r.Attempt != 1 {
+		t.Errorf("expected attempt: 1, actual: %d", r.Attempt)
+	}
+}
+
+func TestQueueExpireFull(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(1, time.Minute)
+	q.Offer(Job{Workflow: "wf1", Name: "a"})
+	j := pollTestQueue(t, q)
+	q.Offer(Job{Workflow: "wf1", Name: "b"})
+
+	// an expired job loses its lease, and waits until there is room
+	if n := q.Expire(time.Now().Add(time.Minute)); n != 0 {
+		t.Errorf("expected no job to be redelivered, actual: %d", n)
+	}
+	if _, err := q.Extend(j.Workflow, j.Name, j.LeaseToken, ""); err != ErrLeaseExpired {
+		t.Errorf("expected %v, actual: %v", ErrLeaseExpired, err)
+	}
+
+	pollTestQueue(t, q)
+	if n := q.Expire(time.Now().Add(time.Minute)); n != 1 {
+		t.Errorf("expected 1 expired job, actual: %d", n)
+	}
+	if r := pollTestQueue(t, q); r.Name != j.Name || r.Attempt != 1 {
+		t.Errorf("expected job %s to be redelivered, actual: %v", j.Name, r)
+	}
+}
+
+func TestQueueExtend(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+	q.Offer(Job{
+		Workflow: "wf1",
+		Name:     "aaa",
+	})
+
+	j := pollTestQueue(t, q)
+	if j.LeaseToken == "" {
+		t.Fatalf("expected a lease token")
+	}
+
+	if _, err := q.Extend(j.Workflow, j.Name, "bad", ""); err != ErrLeaseReassigned {
+		t.Errorf("expected %v, actual: %v", ErrLeaseReassigned, err)
+	}
+
+	r, err := q.Extend(j.Workflow, j.Name, j.LeaseToken, "50%")
+	if err != nil {
+		t.Fatalf("failed to extend lease: %v", err)
+	}
+
+	if r.Progress != "50%" {
+		t.Errorf("expected progress: 50%%, actual: %s", r.Progress)
+	}
+
+	q.Expire(time.Now().Add(time.Minute))
+	if _, err := q.Extend(j.Workflow, j.Name, j.LeaseToken, ""); err != ErrLeaseExpired {
+		t.Errorf("expected %v, actual: %v", ErrLeaseExpired, err)
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+
+	q.Offer(Job{Workflow: "wf1", Name: "bulk1"})
+	q.Offer(Job{Workflow: "wf1", Name: "bulk2"})
+	q.Offer(Job{Workflow: "wf1", Name: "urgent1", Priority: 10})
+	q.Offer(Job{Workflow: "wf1", Name: "bulk3"})
+	q.Offer(Job{Workflow: "wf1", Name: "urgent2", Priority: 10})
+
+	// a re-offer raises the priority, but never lowers it
+	q.Offer(Job{Workflow: "wf1", Name: "bulk3", Priority: 5})
+	q.Offer(Job{Workflow: "wf1", Name: "urgent1"})
+
+	expected := []string{"urgent1", "urgent2", "bulk3", "bulk1", "bulk2"}
+	for _, name := range expected {
+		if j := pollTestQueue(t, q); j.Name != name {
+			t.Errorf("expected job %s, actual: %s", name, j.Name)
+		}
+	}
+}
+
+func TestQueueRaise(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+	q.Offer(Job{Workflow: "wf1", Name: "a", Priority: 1})
+	q.Offer(Job{Workflow: "wf1", Name: "b"})
+	q.Offer(Job{Workflow: "wf1", Name: "c"})
+
+	q.Raise("wf1", "c", 5)
+	q.Raise("wf1", "a", 0)
+	if j, ok := q.Waiting("wf1", "c"); !ok || j.Priority != 5 {
+		t.Errorf("expected job c to wait with priority 5, actual: %v", j)
+	}
+
+	// a polled job is redelivered with its raised priority
+	j := pollTestQueue(t, q)
+	if j.Name != "c" {
+		t.Errorf("expected job c, actual: %s", j.Name)
+	}
+	q.Raise("wf1", "c", 7)
+	q.Expire(time.Now().Add(time.Minute))
+
+	expected := []string{"c", "a", "b"}
+	for _, name := range expected {
+		if j := pollTestQueue(t, q); j.Name != name {
+			t.Errorf("expected job %s, actual: %s", name, j.Name)
+		} else if name == "c" && j.Priority != 7 {
+			t.Errorf("expected job c with priority 7, actual: %d", j.Priority)
+		}
+	}
+}
+
+func TestQueuePriorityRedeliver(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+
+	q.Offer(Job{Workflow: "wf1", Name: "a", Priority: 1})
+	q.Offer(Job{Workflow: "wf1", Name: "b", Priority: 1})
+	q.Offer(Job{Workflow: "wf1", Name: "c", Priority: 2})
+
+	pollTestQueue(t, q)
+	pollTestQueue(t, q)
+	q.Expire(time.Now().Add(time.Hour))
+
+	// the expired jobs go to the head of their priority
+	q.Offer(Job{Workflow: "wf1", Name: "d", Priority: 2})
+	expected := []string{"c", "d", "a", "b"}
+	for _, name := range expected {
+		if j := pollTestQueue(t, q); j.Name != name {
+			t.Errorf("expected job %s, actual: %s", name, j.Name)
+		}
+	}
+}
+
+func TestQueueAdmit(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(2, time.Minute)
+	q.Offer(Job{Workflow: "wf1", Name: "a"})
+	q.Offer(Job{Workflow: "wf1", Name: "b"})
+
+	if err := q.Admit(Job{Workflow: "wf1", Name: "c"}); err != ErrQueueFull {
+		t.Errorf("expected the queue to be full, actual: %v", err)
+	}
+
+	// a job that is already waiting is deduplicated
+	if err := q.Admit(Job{Workflow: "wf1", Name: "a"}); err != nil {
+		t.Errorf("expected a waiting job to be admitted, actual: %v", err)
+	}
+
+	// jobs from the log are accepted over capacity without blocking
+	q.Offer(Job{Workflow: "wf1", Name: "c"})
+
+	pollTestQueue(t, q)
+	pollTestQueue(t, q)
+	if err := q.Admit(Job{Workflow: "wf1", Name: "d"}); err != nil {
+		t.Errorf("expected a job to be admitted, actual: %v", err)
+	}
+
+	// an admitted job holds its room until it is released
+	if err := q.Admit(Job{Workflow: "wf1", Name: "e"}); err != ErrQueueFull {
+		t.Errorf("expected the reserved room to fill the queue, actual: %v", err)
+	}
+	q.Unreserve(Job{Workflow: "wf1", Name: "d"})
+	if err := q.Admit(Job{Workflow: "wf1", Name: "e"}); err != nil {
+		t.Errorf("expected a job to be admitted, actual: %v", err)
+	}
+}
+
+func TestQueueOfferPolled(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+	q.Offer(Job{Workflow: "wf1", Name: "a"})
+	j := pollTestQueue(t, q)
+
+	// a duplicate event does not queue a polled job again
+	q.Offer(Job{Workflow: "wf1", Name: "a"})
+	q.Offer(Job{Workflow: "wf1", Name: "a", LeaseToken: "stale"})
+	if _, ok := q.TryPoll(); ok {
+		t.Fatalf("expected the polled job not to be queued again")
+	}
+
+	// the event that ends the lease queues the job again
+	q.Offer(Job{Workflow: "wf1", Name: "a", LeaseToken: j.LeaseToken})
+	if _, ok := q.Hidden("wf1", "a"); ok {
+		t.Errorf("expected the lease to end")
+	}
+	if r := pollTestQueue(t, q); r.LeaseToken == j.LeaseToken {
+		t.Errorf("expected a new lease, actual: %v", r)
+	}
+}
+
+func TestQueueLongPoll(t *testing.T) {
+	t.Parallel()
+
+	q := NewQueue(100, time.Minute)
+
+	if _, ok := q.TryPoll(); ok {
+		t.Errorf("expected no job in an empty queue")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Poll(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the poll to time out, actual: %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Offer(Job{Workflow: "wf1", Name: "a"})
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	j, err := q.Poll(ctx)
+	if err != nil || j.Name != "a" {
+		t.Errorf("expected job a, actual: %v, %v", j, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the poll to be woken by the offer")
+	}
+
+	// a context that is done peaks without waiting
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if j, err := q.Peak(done); err != context.Canceled {
+		t.Errorf("expected no job to peak, actual: %v, %v", j, err)
 	}
 }
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// defaultPageSize is the page size of ListJobs when it is not set
+	defaultPageSize = 100
+	// maxPageSize caps the page size of ListJobs
+	maxPageSize = 1000
+)
+
+// ErrInvalidCursor is returned when a cursor is not the next cursor of a page
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// prefixes of the job index entries, one for each way jobs are listed
+const (
+	indexWorkflow = 'w'
+	indexState    = 's'
+	indexLabel    = 'l'
+)
+
+// JobRecords is the current record of each job, as of its last event in the
+// log, with secondary indexes to list the jobs of a workflow by state and
+// label in submit time order. Every index entry ends with the submit time
+// and the name of the job, so that a cursor is a position in any index.
+type JobRecords struct {
+	store *Store
+}
+
+// NewJobRecords creates the job records persisted in the store
+func NewJobRecords(store *Store) *JobRecords {
+	return &JobRecords{store: store}
+}
+
+// indexPrefix returns the prefix of the index entries of a workflow, with
+// the parts of an index
+func indexPrefix(index byte, workflow string, parts ...string) []byte {
+	k := []byte{index}
+	k = append(k, workflow...)
+	k = append(k, 0)
+	for _, p := range parts {
+		k = append(k, p...)
+		k = append(k, 0)
+	}
+
+	return k
+}
+
+// indexPosition returns the part of an index entry with the submit time and
+// the name of a job
+func indexPosition(submittedAt int64, name string) []byte {
+	k := make([]byte, 8, 8+len(name))
+	binary.BigEndian.PutUint64(k, uint64(submittedAt))
+	return append(k, name...)
+}
+
+// parseIndexPosition returns the submit time and the name of a job in the
+// part of an index entry after its prefix
+func parseIndexPosition(suffix []byte) (int64, string, error) {
+	if len(suffix) < 8 {
+		return 0, "", fmt.Errorf("invalid index entry %q", suffix)
+	}
+
+	return int64(binary.BigEndian.Uint64(suffix)), string(suffix[8:]), nil
+}
+
+// indexKeys returns the index entries of a job
+func indexKeys(j *Job) [][]byte {
+	pos := indexPosition(j.SubmittedAt, j.Name)
+
+	keys := [][]byte{
+		append(indexPrefix(indexWorkflow, j.Workflow), pos...),
+		append(indexPrefix(indexState, j.Workflow, j.State), pos...),
+	}
+	for k, v := range j.Labels {
+		keys = append(keys, append(indexPrefix(indexLabel, j.Workflow, k, v), pos...))
+	}
+
+	return keys
+}
+
+// Put replaces the record of a job with the job of its last event
+func (r *JobRecords) Put(job Job) error {
+	key := []byte(jobKey(job.Workflow, job.Name))
+
+	prev, err := r.Get(job.Workflow, job.Name)
+	if err != nil {
+		return err
+	}
+
+	// the lease of a job is not part of its record
+	job.LeaseToken = ""
+	data, err := proto.Marshal(&job)
+	if err != nil {
+		return err
+	}
+
+	var remove [][]byte
+	if prev != nil {
+		remove = indexKeys(prev)
+	}
+
+	return r.store.PutJob(key, data, remove, indexKeys(&job))
+}
+
+// Get returns the record of a job, or nil if the job does not exist
+func (r *JobRecords) Get(workflow string, name string) (*Job, error) {
+	data, err := r.store.Job([]byte(jobKey(workflow, name)))
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	j := &Job{}
+	if err := proto.Unmarshal(data, j); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// List returns a page of the jobs that match a request in submit time
+// order. Only the jobs of the partitions that owns returns true for are
+// listed.
+func (r *JobRecords) List(req *ListJobsRequest, owns func(partition int32) bool) ([]*Job, error) {
+	size := pageSize(req.PageSize)
+
+	// the most selective index is scanned, and the jobs are checked
+	// against the rest of the filters
+	prefix := indexPrefix(indexWorkflow, req.Workflow)
+	if req.State != "" {
+		prefix = indexPrefix(indexState, req.Workflow, req.State)
+	} else if len(req.Labels) > 0 {
+		keys := make([]string, 0, len(req.Labels))
+		for k := range req.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		prefix = indexPrefix(indexLabel, req.Workflow, keys[0], req.Labels[keys[0]])
+	}
+
+	start := append(append([]byte(nil), prefix...), indexPosition(req.SubmittedAfter, "")...)
+	if req.Cursor != "" {
+		pos, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+		if err != nil || len(pos) < 8 {
+			return nil, ErrInvalidCursor
+		}
+
+		// the page starts right after the last job of the previous page
+		after := append(append(append([]byte(nil), prefix...), pos...), 0)
+		if bytes.Compare(after, start) > 0 {
+			start = after
+		}
+	}
+
+	var jobs []*Job
+	err := r.store.ScanJobIndex(prefix, start, func(suffix []byte) (bool, error) {
+		submittedAt, name, err := parseIndexPosition(suffix)
+		if err != nil {
+			return false, err
+		}
+		if req.SubmittedBefore > 0 && submittedAt >= req.SubmittedBefore {
+			return false, nil
+		}
+
+		j, err := r.Get(req.Workflow, name)
+		if err != nil {
+			return false, err
+		}
+		if j != nil && matchJob(j, req) && owns(j.Partition) {
+			jobs = append(jobs, j)
+		}
+
+		return len(jobs) < size, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// matchJob tells whether a job matches the filters of a request
+func matchJob(j *Job, req *ListJobsRequest) bool {
+	if req.State != "" && j.State != req.State {
+		return false
+	}
+
+	for k, v := range req.Labels {
+		if l, ok := j.Labels[k]; !ok || l != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pageSize returns the page size of a request
+func pageSize(size int32) int {
+	switch {
+	case size <= 0:
+		return defaultPageSize
+	case size > maxPageSize:
+		return maxPageSize
+	default:
+		return int(size)
+	}
+}
+
+// sortJobs sorts jobs of several members in submit time order
+func sortJobs(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].SubmittedAt != jobs[j].SubmittedAt {
+			return jobs[i].SubmittedAt < jobs[j].SubmittedAt
+		}
+		return jobs[i].Name < jobs[j].Name
+	})
+}
+
+// nextCursor returns the cursor of the page after a full page of jobs, or
+// an empty cursor after the last page
+func nextCursor(jobs []*Job, size int) string {
+	if len(jobs) < size {
+		return ""
+	}
+
+	last := jobs[len(jobs)-1]
+	return base64.RawURLEncoding.EncodeToString(indexPosition(last.SubmittedAt, last.Name))
+}
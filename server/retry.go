@@ -0,0 +1,150 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy retries the failed jobs of a state with an exponential backoff,
+// for example:
+//
+//	retry:
+//	  max_attempts: 5
+//	  initial_backoff: 10s
+//	  multiplier: 2
+//	  max_backoff: 10m
+//	  jitter: 0.2
+//	  non_retryable: [INVALID_INPUT]
+//	  failure_state: review
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one.
+	// Zero retries without limit.
+	MaxAttempts int32 `yaml:"max_attempts"`
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// Multiplier grows the delay of each retry, 2 if not set
+	Multiplier float64 `yaml:"multiplier"`
+	// MaxBackoff caps the delay of the retries when set
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Jitter randomizes the delay by up to this fraction, from 0 to 1
+	Jitter float64 `yaml:"jitter"`
+	// NonRetryable are the error codes that are not retried
+	NonRetryable []string `yaml:"non_retryable"`
+	// FailureState is the state a job moves to when it is not retried.
+	// When empty the job fails.
+	FailureState string `yaml:"failure_state"`
+}
+
+// Validate checks the settings of a retry policy
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("negative max attempts")
+	}
+
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("negative backoff")
+	}
+
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("multiplier %v is less than 1", p.Multiplier)
+	}
+
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("jitter %v is not between 0 and 1", p.Jitter)
+	}
+
+	return nil
+}
+
+// Retryable tells whether a job that failed with an error code after a
+// number of attempts is retried
+func (p *RetryPolicy) Retryable(code string, attempts int32) bool {
+	for _, c := range p.NonRetryable {
+		if c == code {
+			return false
+		}
+	}
+
+	return p.MaxAttempts == 0 || attempts < p.MaxAttempts
+}
+
+// Backoff returns the delay before the retry that follows a number of
+// attempts. The delay is capped by the max backoff after the jitter, and
+// saturates instead of overflowing without a max backoff.
+func (p *RetryPolicy) Backoff(attempts int32) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	max := float64(math.MaxInt64)
+	if p.MaxBackoff > 0 {
+		max = float64(p.MaxBackoff)
+	}
+
+	d := clampBackoff(float64(p.InitialBackoff)*math.Pow(multiplier, float64(attempts-1)), max)
+	if p.Jitter > 0 {
+		d = clampBackoff(d+d*p.Jitter*(2*rand.Float64()-1), max)
+	}
+
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return time.Duration(d)
+}
+
+// clampBackoff bounds a delay between zero and max. The delay of a zero
+// initial backoff that grows to infinity is not a number, and is zero.
+func clampBackoff(d float64, max float64) float64 {
+	switch {
+	case math.IsNaN(d) || d < 0:
+		return 0
+	case d > max:
+		return max
+	default:
+		return d
+	}
+}
+
+// notBefore returns the time after a delay in Unix nanoseconds, which
+// saturates instead of overflowing
+func notBefore(now time.Time, d time.Duration) int64 {
+	n := now.UnixNano()
+	if n > 0 && int64(d) > math.MaxInt64-n {
+		return math.MaxInt64
+	}
+
+	return n + int64(d)
+}
+
+// failJob applies the retry policy of the state of a failed job. The job is
+// retried after a backoff, moved to the failure state, or failed.
+func failJob(wf *Workflow, j *Job, code string, now time.Time) {
+	var p *RetryPolicy
+	if wf != nil {
+		if st := wf.State(j.State); st != nil {
+			p = st.Retry
+		}
+	}
+
+	attempts := j.Attempt + 1
+	switch {
+	case p == nil:
+		j.Status = Job_FAILED
+
+	case p.Retryable(code, attempts):
+		j.Attempt = attempts
+		j.NotBefore = notBefore(now, p.Backoff(attempts))
+
+	case p.FailureState != "":
+		j.State = p.FailureState
+		j.Attempt = 0
+		j.NotBefore = 0
+
+	default:
+		j.Status = Job_FAILED
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const testRetryWorkflow = `
+name: billing
+states:
+  - name: charge
+    retry:
+      max_attempts: 3
+      initial_backoff: 10s
+      max_backoff: 30s
+      non_retryable: [DECLINED]
+      failure_state: review
+  - name: review
+    terminal: true
+`
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{InitialBackoff: 10 * time.Second, MaxBackoff: 30 * time.Second}
+	expected := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, d := range expected {
+		if b := p.Backoff(int32(i + 1)); b != d {
+			t.Errorf("expected backoff %v after %d attempts, actual: %v", d, i+1, b)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if b := p.Backoff(1); b < 5*time.Second || b > 15*time.Second {
+			t.Fatalf("expected backoff within jitter of 10s, actual: %v", b)
+		}
+		if b := p.Backoff(10); b > 30*time.Second {
+			t.Fatalf("expected jitter not to exceed the max backoff, actual: %v", b)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffOverflow(t *testing.T) {
+	t.Parallel()
+
+	// the delay saturates without a max backoff
+	p := RetryPolicy{InitialBackoff: time.Second}
+	for _, attempts := range []int32{64, 1100, math.MaxInt32} {
+		if b := p.Backoff(attempts); b != math.MaxInt64 {
+			t.Errorf("expected the max backoff after %d attempts, actual: %v", attempts, b)
+		}
+	}
+
+	p.Jitter = 1
+	for i := 0; i < 100; i++ {
+		if b := p.Backoff(2000); b < 0 {
+			t.Fatalf("expected a positive backoff with jitter, actual: %v", b)
+		}
+	}
+
+	// a zero initial backoff stays zero when the growth is infinite
+	p = RetryPolicy{Multiplier: 10}
+	if b := p.Backoff(2000); b != 0 {
+		t.Errorf("expected no backoff, actual: %v", b)
+	}
+
+	wf, err := ParseWorkflow([]byte(`
+name: billing
+states:
+  - name: charge
+    retry:
+      initial_backoff: 1s
+`))
+	if err != nil {
+		t.Fatalf("failed to parse workflow: %v", err)
+	}
+
+	now := time.Now()
+	j := Job{Workflow: "billing", Name: "a", State: "charge", Attempt: 5000}
+	failJob(wf, &j, "TIMEOUT", now)
+	if j.Status != Job_ACTIVE || j.NotBefore != math.MaxInt64 {
+		t.Errorf("expected the retry to be delayed to the max time, actual: %v", j)
+	}
+}
+
+func TestFailJob(t *testing.T) {
+	t.Parallel()
+
+	wf, err := ParseWorkflow([]byte(testRetryWorkflow))
+	if err != nil {
+		t.Fatalf("failed to parse workflow: %v", err)
+	}
+
+	now := time.Now()
+	j := Job{Workflow: "billing", Name: "a", State: "charge"}
+
+	// the first failures are retried after a backoff
+	for attempt := int32(1); attempt <= 2; attempt++ {
+		failJob(wf, &j, "TIMEOUT", now)
+		if j.Status != Job_ACTIVE || j.State != "charge" || j.Attempt != attempt {
+			t.Fatalf("expected attempt %d to be retried, actual: %v", attempt, j)
+		}
+		if j.NotBefore <= now.UnixNano() {
+			t.Errorf("expected the retry to be delayed, actual: %d", j.NotBefore)
+		}
+	}
+
+	// the retries are exhausted
+	failJob(wf, &j, "TIMEOUT", now)
+	if j.Status != Job_ACTIVE || j.State != "review" || j.Attempt != 0 || j.NotBefore != 0 {
+		t.Errorf("expected the job to move to review, actual: %v", j)
+	}
+
+	j = Job{Workflow: "billing", Name: "b", State: "charge"}
+	failJob(wf, &j, "DECLINED", now)
+	if j.State != "review" {
+		t.Errorf("expected a non-retryable error to move the job to review, actual: %v", j)
+	}
+
+	// the state has no retry policy
+	failJob(wf, &j, "", now)
+	if j.Status != Job_FAILED {
+		t.Errorf("expected the job to fail, actual: %v", j)
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// forwardedHeader marks a request forwarded by another member, which
+	// is always served locally so that requests are forwarded once
+	forwardedHeader = "conductor-forwarded"
+	// redirectHeader is set by smart clients that route requests
+	// themselves. Instead of forwarding the request, the server replies
+	// with the address of the owner in the ownerHeader trailer.
+	redirectHeader = "conductor-redirect"
+	// ownerHeader is the trailer with the address of the member that owns
+	// the job of a request
+	ownerHeader = "conductor-owner"
+)
+
+// Router finds the member that owns the partition of a job, and forwards
+// requests to it
+type Router struct {
+	sync.Mutex
+	transport  LogTransport
+	membership *Membership
+	// conns are the client connections to the other members, by address
+	conns map[string]*grpc.ClientConn
+}
+
+// NewRouter creates a router over the membership view
+func NewRouter(transport LogTransport, membership *Membership) *Router {
+	return &Router{
+		transport:  transport,
+		membership: membership,
+		conns:      make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Route returns a client of the member that owns a job, or nil when the job
+// is owned by this member, its owner is unknown, or the request was already
+// forwarded. Smart clients get an error with the address of the owner.
+func (r *Router) Route(ctx context.Context, workflow string, name string) (JobServiceClient, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md[forwardedHeader]) > 0 {
+		return nil, nil
+	}
+
+	p, err := r.Partition(workflow, name)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, ok := r.membership.Owner(p)
+	if !ok || r.membership.Local(owner) {
+		return nil, nil
+	}
+
+	if len(md[redirectHeader]) > 0 {
+		grpc.SetTrailer(ctx, metadata.Pairs(ownerHeader, owner))
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s is owned by %s", jobKey(workflow, name), owner)
+	}
+
+	return r.client(owner)
+}
+
+// Partition returns the partition of a job
+func (r *Router) Partition(workflow string, name string) (int32, error) {
+	p, err := r.transport.Partition([]byte(jobKey(workflow, name)))
+	if err != nil {
+		return 0, status.Errorf(codes.Unavailable, "failed to find partition of job %s: %v", jobKey(workflow, name), err)
+	}
+
+	return p, nil
+}
+
+// Peers returns clients of the other members, to poll jobs from when this
+// member has none. It returns nothing for forwarded requests and smart
+// clients.
+func (r *Router) Peers(ctx context.Context) []JobServiceClient {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md[forwardedHeader]) > 0 || len(md[redirectHeader]) > 0 {
+		return nil
+	}
+
+	var clients []JobServiceClient
+	for _, address := range r.membership.Peers() {
+		c, err := r.client(address)
+		if err != nil {
+			continue
+		}
+		clients = append(clients, c)
+	}
+
+	return clients
+}
+
+// client returns a client of the member at address
+func (r *Router) client(address string) (JobServiceClient, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	conn, ok := r.conns[address]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(address, grpc.WithInsecure())
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to connect to %s: %v", address, err)
+		}
+		r.conns[address] = conn
+	}
+
+	return NewJobServiceClient(conn), nil
+}
+
+// Close closes the connections to the other members
+func (r *Router) Close() {
+	r.Lock()
+	defer r.Unlock()
+
+	for address, conn := range r.conns {
+		conn.Close()
+		delete(r.conns, address)
+	}
+}
+
+// forwarded returns the context of a request forwarded to another member
+func forwarded(ctx context.Context) context.Context {
+	return metadata.NewOutgoingContext(ctx, metadata.Pairs(forwardedHeader, "true"))
+}
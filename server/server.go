@@ -2,69 +2,172 @@ package server
 
 import (
 	"fmt"
+	"net"
+	"os"
 )
 
-// Config maintains the server configuration
-type Config map[string]string
+// memoryPartitions is the number of partitions of the in-memory log
+const memoryPartitions = 8
 
 // Server represents a server instance
 type Server struct {
 	config  Config
 	context *Context
+	// started tells whether the WAL and API services need to be stopped
+	started bool
 }
 
 // Context contains business logic context of the server
 type Context struct {
-	store *Store
-	wal   *Wal
-	api   *API
+	store      *Store
+	memstore   *MemStore
+	transport  LogTransport
+	membership *Membership
+	wal        *Wal
+	producer   *Producer
+	api        *API
 }
 
-// NewServer creates a new server instance
-func NewServer(cfg Config) *Server {
-	store := NewStore(cfg["name"])
+// NewServer creates a new server instance. It validates the config and
+// opens the store, and fails if any of them cannot be done.
+func NewServer(cfg Config) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+
+	store := NewStore(cfg.Name, cfg.DataDir)
+	if store == nil {
+		return nil, fmt.Errorf("failed to create store for %s", cfg.Name)
+	}
+
 	if err := store.Open(); err != nil {
-		fmt.Printf("Failed to open store. Error: %s", err.Error())
-		return nil
+		return nil, fmt.Errorf("failed to open store: %v", err)
+	}
+
+	workflows := NewWorkflows(store)
+	if err := workflows.Load(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to load workflows: %v", err)
 	}
 
-	wal := NewWal(cfg["name"], cfg["broker"], store)
-	api := NewAPI()
+	// the jobs of a partition are recovered when it is assigned
+	memstore := NewMemStore(store, cfg.QueueSize, cfg.LeaseTimeout, workflows)
+
+	transport, err := newTransport(cfg)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to create %s transport: %v", cfg.Transport, err)
+	}
+
+	address, err := advertiseAddress(cfg)
+	if err != nil {
+		transport.Close()
+		store.Close()
+		return nil, err
+	}
+
+	membership := NewMembership(address)
+	producer := newProducer(transport)
+	wal := NewWal(transport, store, memstore, membership)
+	wal.verbose = cfg.Log.Level == LogDebug
+	api := NewAPI(cfg.ListenAddress, producer, memstore, workflows, NewRouter(transport, membership))
 
 	ctx := Context{
-		store: store,
-		wal:   wal,
-		api:   api,
+		store:      store,
+		memstore:   memstore,
+		transport:  transport,
+		membership: membership,
+		wal:        wal,
+		producer:   producer,
+		api:        api,
 	}
 
-	fmt.Printf("Store for %s", cfg["name"])
+	fmt.Printf("Store for %s\n", cfg.Name)
 
 	return &Server{
 		config:  cfg,
 		context: &ctx,
+	}, nil
+}
+
+// newTransport creates the log transport selected by the config
+func newTransport(cfg Config) (LogTransport, error) {
+	switch cfg.Transport {
+	case "kafka":
+		settings, err := cfg.Kafka.ConfigMap()
+		if err != nil {
+			return nil, err
+		}
+		return NewKafkaTransport(cfg.Name, cfg.Kafka.Brokers, settings)
+
+	case "memory":
+		return sharedMemoryTransport(cfg.Name, memoryPartitions), nil
+
+	default:
+		return nil, fmt.Errorf("unknown transport %s", cfg.Transport)
+	}
+}
+
+// advertiseAddress returns the address of the API for the other members
+func advertiseAddress(cfg Config) (string, error) {
+	if cfg.AdvertiseAddress != "" {
+		return cfg.AdvertiseAddress, nil
+	}
+
+	_, port, err := net.SplitHostPort(cfg.ListenAddress)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %s: %v", cfg.ListenAddress, err)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get host name: %v", err)
 	}
+
+	return net.JoinHostPort(host, port), nil
 }
 
-// Start starts the server
-func (s *Server) Start() {
+// Start starts the server. When it fails, Stop releases the services
+// that were started.
+func (s *Server) Start() error {
 	fmt.Println("Starting server...")
-	s.context.wal.Start()
-	s.context.api.Start()
+	s.context.memstore.Start()
+
+	if err := s.context.wal.Start(); err != nil {
+		return err
+	}
+
+	s.started = true
+	return s.context.api.Start()
+}
+
+// Err returns a channel that receives the error of a service that failed
+// while the server runs. The server is then stopped, since it no longer
+// applies the jobs it publishes.
+func (s *Server) Err() <-chan error {
+	return s.context.wal.Err()
 }
 
 // Stop shuts down the server
 func (s *Server) Stop() {
 	fmt.Println("Stopping server...")
 
-	if s.context.store != nil {
-		s.context.store.Close()
+	if s.started {
+		s.context.api.Stop()
+		s.context.wal.Stop()
+		s.started = false
 	}
 
-	if s.context.wal != nil {
-		s.context.wal.Stop()
+	if s.context.transport != nil {
+		s.context.transport.Close()
 	}
 
-	if s.context.api != nil {
-		s.context.api.Stop()
+	// the memstore saves a last checkpoint, so it stops before the store
+	if s.context.memstore != nil {
+		s.context.memstore.Stop()
+	}
+
+	if s.context.store != nil {
+		s.context.store.Close()
 	}
 }
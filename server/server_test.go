@@ -2,23 +2,712 @@ package server
 
 import (
 	"fmt"
+	"os"
 	"testing"
 	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestServerStartStop(t *testing.T) {
 	t.Parallel()
 
-	config := Config{
-		"broker": "localhost:9092",
-		"name":   fmt.Sprintf("TestServerStartStop-%d", time.Now().Unix()),
-	}
-	server := NewServer(config)
-	if server == nil {
-		t.FailNow()
+	config := DefaultConfig()
+	config.Transport = "memory"
+	config.Name = fmt.Sprintf("TestServerStartStop-%d", time.Now().Unix())
+	// any free port, since tests run in parallel
+	config.ListenAddress = "localhost:0"
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
 	}
+	defer os.RemoveAll(server.context.store.path)
 
-	server.Start()
+	if err := server.Start(); err != nil {
+		server.Stop()
+		t.Fatalf("failed to start server: %v", err)
+	}
 	time.Sleep(3 * time.Second)
 	server.Stop()
 }
+
+// newTestServer creates a server on the in-memory log, and starts the
+// services but the API, so that tests call the API directly
+func newTestServer(t *testing.T) *Server {
+	server := newTestMember(t, "")
+
+	// jobs are only served once the partitions are assigned
+	for p := int32(0); p < memoryPartitions; p++ {
+		for i := 0; !server.context.memstore.Ready(p); i++ {
+			if i == 100 {
+				t.Fatalf("partition %d is not assigned", p)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	_, err := server.context.api.PutWorkflow(context.Background(), &WorkflowDefinition{
+		Definition: testWorkflow,
+	})
+	if err != nil {
+		t.Fatalf("failed to put workflow: %v", err)
+	}
+
+	return server
+}
+
+// newTestMember creates a server that shares the in-memory log of the
+// test with the other members, advertised at an address
+func newTestMember(t *testing.T, address string) *Server {
+	config := DefaultConfig()
+	config.Transport = "memory"
+	config.Name = t.Name()
+	config.AdvertiseAddress = address
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	server.context.memstore.Start()
+	if err := server.context.wal.Start(); err != nil {
+		t.Fatalf("failed to start WAL: %v", err)
+	}
+
+	return server
+}
+
+// stopTestServer stops the services started by newTestServer, and removes
+// the temporary data directory of the server
+func stopTestServer(server *Server) {
+	server.context.wal.Stop()
+	server.context.transport.Close()
+	server.context.memstore.Stop()
+	server.context.store.Close()
+	os.RemoveAll(server.context.store.path)
+}
+
+// waitTestPeers waits until a member sees a number of peers
+func waitTestPeers(t *testing.T, server *Server, n int) {
+	for i := 0; len(server.context.membership.Peers()) != n; i++ {
+		if i == 100 {
+			t.Fatalf("expected %d peers, actual: %v", n, server.context.membership.Peers())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestServerMembers(t *testing.T) {
+	t.Parallel()
+
+	a := newTestMember(t, "a:1")
+	defer stopTestServer(a)
+	b := newTestMember(t, "b:1")
+	waitTestPeers(t, a, 1)
+
+	// a member that stops drops out of the view of the others
+	stopTestServer(b)
+	waitTestPeers(t, a, 0)
+}
+
+func TestServerWorkflowMembers(t *testing.T) {
+	t.Parallel()
+
+	a := newTestMember(t, "a:1")
+	defer stopTestServer(a)
+	b := newTestMember(t, "b:1")
+	defer stopTestServer(b)
+
+	ctx := context.Background()
+	if _, err := a.context.api.PutWorkflow(ctx, &WorkflowDefinition{Definition: testWorkflow}); err != nil {
+		t.Fatalf("failed to put workflow: %v", err)
+	}
+
+	// a member that joins later reads the definitions from the log too
+	c := newTestMember(t, "c:1")
+	defer stopTestServer(c)
+
+	for _, member := range []*Server{b, c} {
+		var err error
+		for i := 0; i < 100; i++ {
+			if _, err = member.context.api.GetWorkflow(ctx, &WorkflowDefinition{Name: "etl"}); err == nil {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err != nil {
+			t.Errorf("expected workflow etl on %s, actual: %v", member.context.membership.address, err)
+		}
+	}
+}
+
+// pollTestJob long-polls a job until it is consumed from the log
+func pollTestJob(t *testing.T, api *API, workflow string, state string) *Job {
+	j, err := api.PollJob(context.Background(), &PollRequest{Workflow: workflow, State: state, WaitMs: 5000})
+	if err != nil {
+		t.Fatalf("no job in %s:%s: %v", workflow, state, err)
+	}
+
+	return j
+}
+
+func TestServerBatch(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: name, State: "extract"}); err != nil {
+			t.Fatalf("failed to add job: %v", err)
+		}
+	}
+
+	if _, err := api.PollJobs(ctx, &BatchPollRequest{Workflow: "etl", State: "extract"}); err == nil {
+		t.Errorf("expected a batch without max to be rejected")
+	}
+
+	var jobs []*Job
+	for len(jobs) < 3 {
+		l, err := api.PollJobs(ctx, &BatchPollRequest{Workflow: "etl", State: "extract", Max: 2, WaitMs: 5000})
+		if err != nil || len(l.Jobs) == 0 || len(l.Jobs) > 2 {
+			t.Fatalf("expected 1 or 2 jobs, actual: %v, %v", l, err)
+		}
+		jobs = append(jobs, l.Jobs...)
+	}
+
+	r := &BatchCompleteRequest{}
+	for _, j := range jobs {
+		r.Requests = append(r.Requests, &CompleteRequest{Workflow: "etl", Name: j.Name, NextState: "transform", LeaseToken: j.LeaseToken})
+	}
+	r.Requests[2].LeaseToken = "stale"
+
+	res, err := api.CompleteJobs(ctx, r)
+	if err != nil || len(res.Results) != 3 {
+		t.Fatalf("expected 3 results, actual: %v, %v", res, err)
+	}
+	for i, jr := range res.Results[:2] {
+		if jr.Code != 0 || jr.Job == nil || jr.Job.State != "transform" {
+			t.Errorf("expected job %d to complete, actual: %v", i, jr)
+		}
+	}
+	if res.Results[2].Code == 0 || res.Results[2].Job != nil {
+		t.Errorf("expected a stale lease to be rejected, actual: %v", res.Results[2])
+	}
+}
+
+func TestServerJobLifecycle(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "unknown"}); err == nil {
+		t.Errorf("expected a job in an unknown state to be rejected")
+	}
+
+	if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "extract"}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+
+	j := pollTestJob(t, api, "etl", "extract")
+	if j.Name != "a" {
+		t.Errorf("expected job a, actual: %s", j.Name)
+	}
+
+	_, err := api.CompleteJob(ctx, &CompleteRequest{Workflow: "etl", Name: "a", NextState: "load", LeaseToken: j.LeaseToken})
+	if err == nil {
+		t.Errorf("expected an illegal transition to be rejected")
+	}
+
+	_, err = api.CompleteJob(ctx, &CompleteRequest{Workflow: "etl", Name: "a", NextState: "transform", LeaseToken: j.LeaseToken})
+	if err != nil {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+
+	j = pollTestJob(t, api, "etl", "transform")
+	if _, err := api.ExtendLease(ctx, &LeaseRequest{Workflow: "etl", Name: "a", LeaseToken: j.LeaseToken, Progress: "half"}); err != nil {
+		t.Errorf("failed to extend lease: %v", err)
+	}
+
+	_, err = api.FailJob(ctx, &FailRequest{Workflow: "etl", Name: "a", Error: "boom", LeaseToken: j.LeaseToken})
+	if err != nil {
+		t.Fatalf("failed to fail job: %v", err)
+	}
+
+	if _, err := api.ExtendLease(ctx, &LeaseRequest{Workflow: "etl", Name: "a", LeaseToken: j.LeaseToken}); err == nil {
+		t.Errorf("expected the lease of a failed job to be rejected")
+	}
+}
+
+func TestServerLeaseToken(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "extract"}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+	first := pollTestJob(t, api, "etl", "extract")
+
+	// the lease of the first worker expires, and the job is redelivered
+	server.context.memstore.expire(time.Now().Add(time.Hour))
+	second := pollTestJob(t, api, "etl", "extract")
+
+	_, err := api.CompleteJob(ctx, &CompleteRequest{Workflow: "etl", Name: "a", NextState: "transform"})
+	if s, _ := status.FromError(err); s.Code() != codes.FailedPrecondition {
+		t.Errorf("expected a complete without lease token to be rejected, actual: %v", err)
+	}
+
+	_, err = api.FailJob(ctx, &FailRequest{Workflow: "etl", Name: "a", Error: "boom"})
+	if s, _ := status.FromError(err); s.Code() != codes.FailedPrecondition {
+		t.Errorf("expected a fail without lease token to be rejected, actual: %v", err)
+	}
+
+	_, err = api.CompleteJob(ctx, &CompleteRequest{Workflow: "etl", Name: "a", NextState: "transform", LeaseToken: first.LeaseToken})
+	if s, _ := status.FromError(err); s.Code() != codes.Aborted {
+		t.Errorf("expected the expired lease to be rejected, actual: %v", err)
+	}
+
+	_, err = api.CompleteJob(ctx, &CompleteRequest{Workflow: "etl", Name: "a", NextState: "transform", LeaseToken: second.LeaseToken})
+	if err != nil {
+		t.Errorf("failed to complete job with the current lease: %v", err)
+	}
+}
+
+func TestServerAddJobFields(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	// the fields owned by the server are not taken from the client
+	j, err := api.AddJob(ctx, &Job{
+		Workflow:      "etl",
+		Name:          "a",
+		State:         "extract",
+		Data:          "payload",
+		Status:        Job_COMPLETED,
+		Attempt:       3,
+		LeaseToken:    "token",
+		Error:         "boom",
+		Progress:      "half",
+		PreviousState: "load",
+		Partition:     7,
+		Offset:        99,
+	})
+	if err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+	if j.Status != Job_ACTIVE || j.Attempt != 0 || j.LeaseToken != "" || j.Error != "" || j.Progress != "" || j.PreviousState != "" || j.Partition == 7 || j.Offset == 99 {
+		t.Errorf("expected an active job without server fields, actual: %v", j)
+	}
+
+	p := pollTestJob(t, api, "etl", "extract")
+	if p.Name != "a" || p.Data != "payload" || p.Status != Job_ACTIVE || p.Attempt != 0 || p.Error != "" {
+		t.Errorf("expected active job a to be polled, actual: %v", p)
+	}
+}
+
+func TestServerNotOwner(t *testing.T) {
+	t.Parallel()
+
+	a := newTestServer(t)
+	defer stopTestServer(a)
+
+	// a member that has not joined the group yet owns no partition
+	config := DefaultConfig()
+	config.Transport = "memory"
+	config.Name = t.Name()
+	config.AdvertiseAddress = "b:1"
+	b, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer os.RemoveAll(b.context.store.path)
+	defer b.Stop()
+
+	b.context.api.workflows.Put([]byte(testWorkflow))
+	_, err = b.context.api.AddJob(context.Background(), &Job{Workflow: "etl", Name: "a", State: "extract"})
+	if s, _ := status.FromError(err); s.Code() != codes.Unavailable {
+		t.Errorf("expected a job of a partition without owner to be unavailable, actual: %v", err)
+	}
+}
+
+func TestServerAddJobActive(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "extract"}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+	pollTestJob(t, api, "etl", "extract")
+
+	// an active job cannot skip the transition of its state
+	for _, state := range []string{"extract", "load"} {
+		_, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: state})
+		if s, _ := status.FromError(err); s.Code() != codes.AlreadyExists {
+			t.Errorf("expected active job a to be rejected in %s, actual: %v", state, err)
+		}
+	}
+
+	// a finished job can be added again
+	if _, err := api.CancelJob(ctx, &CancelRequest{Workflow: "etl", Name: "a"}); err != nil {
+		t.Fatalf("failed to cancel job: %v", err)
+	}
+	for i := 0; ; i++ {
+		_, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "load"})
+		if err == nil {
+			break
+		}
+		if s, _ := status.FromError(err); s.Code() != codes.AlreadyExists || i == 100 {
+			t.Fatalf("expected a finished job to be added again, actual: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestServerAddJobPriority(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "extract", Data: "a"}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+	for i := 0; ; i++ {
+		if _, ok := server.context.memstore.Waiting("etl", "a"); ok {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("expected job a to be waiting")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// a waiting job is added again to raise its priority
+	j, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "extract", Priority: 5})
+	if err != nil || j.Priority != 5 || j.Data != "a" {
+		t.Fatalf("expected the priority of job a to be raised, actual: %v, %v", j, err)
+	}
+
+	for i := 0; ; i++ {
+		if w, _ := server.context.memstore.Waiting("etl", "a"); w.Priority == 5 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("expected job a to wait with priority 5")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// a lower priority or another state is not an update
+	for _, r := range []*Job{
+		{Workflow: "etl", Name: "a", State: "extract", Priority: 3},
+		{Workflow: "etl", Name: "a", State: "load", Priority: 9},
+	} {
+		_, err := api.AddJob(ctx, r)
+		if s, _ := status.FromError(err); s.Code() != codes.AlreadyExists {
+			t.Errorf("expected %v to be rejected, actual: %v", r, err)
+		}
+	}
+	if j := pollTestJob(t, api, "etl", "extract"); j.Priority != 5 || j.Data != "a" {
+		t.Errorf("expected job a with priority 5, actual: %v", j)
+	}
+}
+
+// waitTestDeadLetters waits until a workflow has a number of dead-lettered
+// jobs
+func waitTestDeadLetters(t *testing.T, api *API, workflow string, n int) []*DeadLetter {
+	for i := 0; i < 100; i++ {
+		l, err := api.ListDeadLetters(context.Background(), &DeadLetterRequest{Workflow: workflow})
+		if err != nil {
+			t.Fatalf("failed to list dead letters: %v", err)
+		}
+		if len(l.Entries) == n {
+			return l.Entries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %d dead-lettered jobs in %s", n, workflow)
+	return nil
+}
+
+func TestServerDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "extract"}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+
+	j := pollTestJob(t, api, "etl", "extract")
+	if _, err := api.FailJob(ctx, &FailRequest{Workflow: "etl", Name: "a", Error: "boom", LeaseToken: j.LeaseToken}); err != nil {
+		t.Fatalf("failed to fail job: %v", err)
+	}
+
+	// a message that is not a job
+	if _, err := server.context.transport.Produce([]byte("etl:b"), []byte("not a job")); err != nil {
+		t.Fatalf("failed to produce: %v", err)
+	}
+
+	entries := waitTestDeadLetters(t, api, "etl", 2)
+	if entries[0].Name != "a" || entries[0].Error != "boom" || entries[0].Payload != nil {
+		t.Errorf("expected job a without payload, actual: %v", entries[0])
+	}
+
+	dl, err := api.GetDeadLetter(ctx, &DeadLetterRequest{Workflow: "etl", Name: "b"})
+	if err != nil || string(dl.Payload) != "not a job" {
+		t.Errorf("expected the payload of b, actual: %v, %v", dl, err)
+	}
+
+	if _, err := api.RedriveJob(ctx, &RedriveRequest{Workflow: "etl", Name: "b"}); err == nil {
+		t.Errorf("expected a message that is not a job not to be redriven")
+	}
+
+	if _, err := api.RedriveJob(ctx, &RedriveRequest{Workflow: "etl", Name: "a", State: "transform"}); err != nil {
+		t.Fatalf("failed to redrive job: %v", err)
+	}
+
+	if j := pollTestJob(t, api, "etl", "transform"); j.Name != "a" || j.Error != "" {
+		t.Errorf("expected job a to be redriven, actual: %v", j)
+	}
+	waitTestDeadLetters(t, api, "etl", 1)
+
+	// the entries of a partition that is no longer owned are not listed
+	server.context.memstore.Revoke([]int32{dl.Partition})
+	if l, err := api.ListDeadLetters(ctx, &DeadLetterRequest{Workflow: "etl"}); err != nil || len(l.Entries) != 0 {
+		t.Errorf("expected no dead letters, actual: %v, %v", l, err)
+	}
+}
+
+func TestServerJobHistory(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	if _, err := api.GetJobHistory(ctx, &JobHistoryRequest{Workflow: "etl", Name: "a"}); err == nil {
+		t.Errorf("expected no history of an unknown job")
+	}
+
+	if _, err := api.AddJob(ctx, &Job{Workflow: "etl", Name: "a", State: "extract"}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+
+	j, err := api.PollJob(ctx, &PollRequest{Workflow: "etl", State: "extract", Worker: "w1", WaitMs: 5000})
+	if err != nil {
+		t.Fatalf("failed to poll job: %v", err)
+	}
+	if _, err := api.ExtendLease(ctx, &LeaseRequest{Workflow: "etl", Name: "a", LeaseToken: j.LeaseToken, Progress: "half", Worker: "w1"}); err != nil {
+		t.Fatalf("failed to extend lease: %v", err)
+	}
+	if _, err := api.CompleteJob(ctx, &CompleteRequest{Workflow: "etl", Name: "a", NextState: "transform", LeaseToken: j.LeaseToken}); err != nil {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+	pollTestJob(t, api, "etl", "transform")
+
+	h, err := api.GetJobHistory(ctx, &JobHistoryRequest{Workflow: "etl", Name: "a"})
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+
+	expected := []JobEvent_Type{JobEvent_SUBMITTED, JobEvent_POLLED, JobEvent_HEARTBEAT, JobEvent_TRANSITIONED, JobEvent_POLLED}
+	if len(h.Events) != len(expected) {
+		t.Fatalf("expected %d events, actual: %v", len(expected), h.Events)
+	}
+	for i, e := range h.Events {
+		if e.Type != expected[i] {
+			t.Errorf("expected event %d to be %s, actual: %s", i, expected[i], e.Type)
+		}
+	}
+	if h.Events[1].Worker != "w1" || h.Events[2].Progress != "half" {
+		t.Errorf("expected the poll and heartbeat of w1, actual: %v, %v", h.Events[1], h.Events[2])
+	}
+	if e := h.Events[3]; e.PreviousState != "extract" || e.State != "transform" {
+		t.Errorf("expected a transition from extract to transform, actual: %v", e)
+	}
+}
+
+// listTestJobs lists the jobs of a request until the log is consumed and
+// a number of jobs is listed, following the cursors of the pages
+func listTestJobs(t *testing.T, api *API, r *ListJobsRequest, n int) []*Job {
+	for i := 0; i < 100; i++ {
+		var jobs []*Job
+		req := *r
+		for {
+			p, err := api.ListJobs(context.Background(), &req)
+			if err != nil {
+				t.Fatalf("failed to list jobs: %v", err)
+			}
+			if len(p.Jobs) > int(r.PageSize) {
+				t.Fatalf("expected pages of %d jobs at most, actual: %d", r.PageSize, len(p.Jobs))
+			}
+			jobs = append(jobs, p.Jobs...)
+			if p.NextCursor == "" {
+				break
+			}
+			req.Cursor = p.NextCursor
+		}
+
+		if len(jobs) == n {
+			return jobs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %d jobs listed by %v", n, r)
+	return nil
+}
+
+func TestServerQueryJobs(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	start := time.Now().UnixNano()
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		j := &Job{Workflow: "etl", Name: name, State: "extract", Labels: map[string]string{"team": "x"}}
+		if i%2 == 1 {
+			j.Labels["team"] = "y"
+		}
+		if _, err := api.AddJob(ctx, j); err != nil {
+			t.Fatalf("failed to add job: %v", err)
+		}
+	}
+
+	jobs := listTestJobs(t, api, &ListJobsRequest{Workflow: "etl", PageSize: 2}, 5)
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		if jobs[i].Name != name || jobs[i].SubmittedAt < start {
+			t.Errorf("expected job %s submitted after the test started, actual: %v", name, jobs[i])
+		}
+	}
+
+	jobs = listTestJobs(t, api, &ListJobsRequest{Workflow: "etl", Labels: map[string]string{"team": "y"}, PageSize: 1}, 2)
+	if jobs[0].Name != "b" || jobs[1].Name != "d" {
+		t.Errorf("expected jobs b and d, actual: %v", jobs)
+	}
+
+	listTestJobs(t, api, &ListJobsRequest{Workflow: "etl", SubmittedAfter: jobs[1].SubmittedAt, PageSize: 10}, 2)
+
+	j := pollTestJob(t, api, "etl", "extract")
+	if _, err := api.CompleteJob(ctx, &CompleteRequest{Workflow: "etl", Name: j.Name, NextState: "transform", LeaseToken: j.LeaseToken}); err != nil {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+	jobs = listTestJobs(t, api, &ListJobsRequest{Workflow: "etl", State: "transform", PageSize: 10}, 1)
+	if jobs[0].Name != j.Name {
+		t.Errorf("expected job %s in transform, actual: %v", j.Name, jobs[0])
+	}
+	listTestJobs(t, api, &ListJobsRequest{Workflow: "etl", State: "extract", PageSize: 10}, 4)
+
+	j = pollTestJob(t, api, "etl", "extract")
+	info, err := api.GetJob(ctx, &GetJobRequest{Workflow: "etl", Name: j.Name})
+	if err != nil || !info.Leased || info.Job.LeaseToken != "" || info.LeaseExpiresAt <= time.Now().UnixNano() {
+		t.Errorf("expected job %s to be leased, actual: %v, %v", j.Name, info, err)
+	}
+
+	if _, err := api.GetJob(ctx, &GetJobRequest{Workflow: "etl", Name: "unknown"}); err == nil {
+		t.Errorf("expected an unknown job not to be found")
+	}
+	if _, err := api.ListJobs(ctx, &ListJobsRequest{Workflow: "etl", Cursor: "!"}); err == nil {
+		t.Errorf("expected an invalid cursor to be rejected")
+	}
+}
+
+func TestServerCancel(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	defer stopTestServer(server)
+
+	api := server.context.api
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c"} {
+		j := &Job{Workflow: "etl", Name: name, State: "extract", Labels: map[string]string{"team": "x"}}
+		if name == "c" {
+			j.Labels["team"] = "y"
+		}
+		if _, err := api.AddJob(ctx, j); err != nil {
+			t.Fatalf("failed to add job: %v", err)
+		}
+	}
+	listTestJobs(t, api, &ListJobsRequest{Workflow: "etl", PageSize: 10}, 3)
+
+	j := pollTestJob(t, api, "etl", "extract")
+	if _, err := api.CancelJob(ctx, &CancelRequest{Workflow: "etl", Name: j.Name}); err != nil {
+		t.Fatalf("failed to cancel job: %v", err)
+	}
+
+	// the worker is told on a heartbeat once the cancellation is applied
+	var err error
+	for i := 0; i < 100; i++ {
+		if _, err = api.ExtendLease(ctx, &LeaseRequest{Workflow: "etl", Name: j.Name, LeaseToken: j.LeaseToken}); err != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s, _ := status.FromError(err); s.Code() != codes.Aborted {
+		t.Errorf("expected the lease of a cancelled job to be aborted, actual: %v", err)
+	}
+	if _, err := api.CancelJob(ctx, &CancelRequest{Workflow: "etl", Name: j.Name}); err == nil {
+		t.Errorf("expected a cancelled job not to be cancelled again")
+	}
+
+	res, err := api.CancelJobs(ctx, &BulkCancelRequest{Workflow: "etl", State: "extract", DryRun: true})
+	if err != nil || res.Count != 2 {
+		t.Errorf("expected 2 jobs to cancel, actual: %v, %v", res, err)
+	}
+
+	res, err = api.CancelJobs(ctx, &BulkCancelRequest{Workflow: "etl", Labels: map[string]string{"team": "y"}})
+	if err != nil || res.Count != 1 {
+		t.Errorf("expected 1 job to be cancelled, actual: %v, %v", res, err)
+	}
+
+	for i := 0; i < 100; i++ {
+		res, err = api.CancelJobs(ctx, &BulkCancelRequest{Workflow: "etl", DryRun: true})
+		if err != nil || res.Count == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil || res.Count != 1 {
+		t.Errorf("expected 1 job left to cancel, actual: %v, %v", res, err)
+	}
+}
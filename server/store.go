@@ -1,16 +1,51 @@
 package server
 
 import (
+	"encoding/binary"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/tecbot/gorocksdb"
 )
 
+// indexes of the column families in Store.cf
+const (
+	cfDefault = iota
+	cfWal
+	cfWorkflow
+	cfSnapshot
+	cfOffset
+	cfWalIndex
+	cfTimer
+	cfDeadLetter
+	cfHistory
+	cfJob
+	cfJobIndex
+)
+
+// checkpointKey is the prefix of the keys in the default column family that
+// hold the offset of the last checkpoint of each partition
+var checkpointKey = []byte("checkpoint")
+
+const (
+	// lockFile prevents two processes from opening the same data directory
+	lockFile = "LOCK"
+	// clusterFile records the name of the cluster that owns a data directory
+	clusterFile = "CLUSTER"
+	// dbDir is the directory of the RocksDB database in the data directory
+	dbDir = "db"
+)
+
 // Store is the local RocksDB storage manager
 type Store struct {
 	name string
 	path string
+	lock *os.File
 
 	db          *gorocksdb.DB
 	walWriteOpt *gorocksdb.WriteOptions
@@ -20,28 +55,107 @@ type Store struct {
 	cfh []*gorocksdb.ColumnFamilyHandle
 }
 
-// NewStore creates a new RocksDB database
-func NewStore(name string) *Store {
-	p, err := ioutil.TempDir("", "conductor")
-	if err != nil {
-		return nil
+// NewStore creates a new RocksDB database in the data directory. A
+// temporary directory is used when dir is empty.
+func NewStore(name string, dir string) *Store {
+	if dir == "" {
+		p, err := ioutil.TempDir("", "conductor")
+		if err != nil {
+			return nil
+		}
+		dir = p
 	}
-	_ = os.Mkdir(p, os.ModePerm)
 
 	return &Store{
 		name: name,
-		cf:   []string{"default", "wal"},
-		path: p,
+		cf:   []string{"default", "wal", "workflow", "snapshot", "offset", "walindex", "timer", "deadletter", "history", "job", "jobindex"},
+		path: dir,
 	}
 }
 
-// Open creates rocksdb file
+// Open creates rocksdb file. It fails if the data directory is opened by
+// another process, or belongs to a different cluster.
 func (s *Store) Open() error {
+	if err := os.MkdirAll(s.path, os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := s.acquireLock(); err != nil {
+		return err
+	}
+
+	if err := s.checkCluster(); err != nil {
+		s.releaseLock()
+		return err
+	}
+
+	if err := s.openDB(); err != nil {
+		s.releaseLock()
+		return err
+	}
+
+	return nil
+}
+
+// acquireLock takes an exclusive lock on the data directory
+func (s *Store) acquireLock() error {
+	f, err := os.OpenFile(filepath.Join(s.path, lockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return fmt.Errorf("data directory %s is used by another process", s.path)
+	}
+
+	s.lock = f
+	return nil
+}
+
+// releaseLock releases the lock on the data directory
+func (s *Store) releaseLock() {
+	if s.lock == nil {
+		return
+	}
+
+	syscall.Flock(int(s.lock.Fd()), syscall.LOCK_UN)
+	s.lock.Close()
+	s.lock = nil
+}
+
+// checkCluster makes sure that the data directory belongs to the cluster
+// of this store. The cluster name is recorded on first use.
+func (s *Store) checkCluster() error {
+	p := filepath.Join(s.path, clusterFile)
+
+	data, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return ioutil.WriteFile(p, []byte(s.name+"\n"), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if name := strings.TrimSpace(string(data)); name != s.name {
+		return fmt.Errorf("data directory %s belongs to cluster %s, not %s", s.path, name, s.name)
+	}
+
+	return nil
+}
+
+// openDB opens the RocksDB database with all column families
+func (s *Store) openDB() error {
 	opts := gorocksdb.NewDefaultOptions()
 	opts.SetCreateIfMissing(true)
 	opts.SetCreateIfMissingColumnFamilies(true)
 
-	db, cfh, err := gorocksdb.OpenDbColumnFamilies(opts, s.path, s.cf, []*gorocksdb.Options{opts, opts})
+	cfOpts := make([]*gorocksdb.Options, len(s.cf))
+	for i := range cfOpts {
+		cfOpts[i] = opts
+	}
+
+	db, cfh, err := gorocksdb.OpenDbColumnFamilies(opts, filepath.Join(s.path, dbDir), s.cf, cfOpts)
 	if err != nil {
 		opts.Destroy()
 		return err
@@ -49,14 +163,461 @@ func (s *Store) Open() error {
 
 	s.db = db
 	s.cfh = cfh
+	// the log offsets are committed once the WAL entry is written, so
+	// the write must survive a crash
 	s.walWriteOpt = gorocksdb.NewDefaultWriteOptions()
+	s.walWriteOpt.SetSync(true)
 
 	return nil
 }
 
-// AppendWAL appends a Kafka message to the WAL CF.
-func (s *Store) AppendWAL(key []byte, value []byte) {
-	s.db.PutCF(s.walWriteOpt, s.cfh[1], key, value)
+// walKey returns the key of the WAL entry of a log message. Entries are
+// keyed by partition and offset, so that every message maps to exactly one
+// entry, and the entries of a partition are iterated in log order.
+func walKey(partition int32, offset int64) []byte {
+	key := make([]byte, 12)
+	binary.BigEndian.PutUint32(key, uint32(partition))
+	binary.BigEndian.PutUint64(key[4:], uint64(offset))
+
+	return key
+}
+
+// parseWalKey returns the partition and offset of a WAL key
+func parseWalKey(key []byte) (int32, int64) {
+	return int32(binary.BigEndian.Uint32(key)), int64(binary.BigEndian.Uint64(key[4:]))
+}
+
+// walIndexKey returns the key of the WAL index entry of a log message. The
+// index is ordered by job key and message timestamp, and ends with the WAL
+// key of the message.
+func walIndexKey(key []byte, timestamp time.Time, partition int32, offset int64) []byte {
+	k := make([]byte, 0, len(key)+21)
+	k = append(k, key...)
+	k = append(k, 0)
+
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(timestamp.UnixNano()))
+	k = append(k, ts...)
+
+	return append(k, walKey(partition, offset)...)
+}
+
+// AppendWAL appends a message of the log to the WAL CF, with an index
+// entry for the job key of the message. The offset of the message is
+// stored in the same write, so that the message is not appended again
+// when the log delivers it another time. The history event of the message
+// is written with it when it is not nil.
+func (s *Store) AppendWAL(partition int32, offset int64, key []byte, timestamp time.Time, value []byte, event []byte) error {
+	wk := walKey(partition, offset)
+
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+
+	wb.PutCF(s.cfh[cfWal], wk, value)
+	wb.PutCF(s.cfh[cfWalIndex], walIndexKey(key, timestamp, partition, offset), nil)
+	wb.PutCF(s.cfh[cfOffset], wk[:4], wk[4:])
+	if event != nil {
+		wb.PutCF(s.cfh[cfHistory], walIndexKey(key, timestamp, partition, offset), event)
+	}
+
+	return s.db.Write(s.walWriteOpt, wb)
+}
+
+// Offsets returns the offset of the last message appended to the WAL, for
+// each partition of the log
+func (s *Store) Offsets() (map[int32]int64, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfOffset])
+	defer it.Close()
+
+	offsets := make(map[int32]int64)
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		k := it.Key()
+		v := it.Value()
+		offsets[int32(binary.BigEndian.Uint32(k.Data()))] = int64(binary.BigEndian.Uint64(v.Data()))
+		k.Free()
+		v.Free()
+	}
+
+	return offsets, it.Err()
+}
+
+// ScanWAL calls fn for each WAL entry of a partition with an offset after
+// the given one, in log order. Use -1 to scan the whole partition.
+func (s *Store) ScanWAL(partition int32, after int64, fn func(offset int64, value []byte) error) error {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfWal])
+	defer it.Close()
+
+	prefix := walKey(partition, 0)[:4]
+	for it.Seek(walKey(partition, after+1)); it.ValidForPrefix(prefix); it.Next() {
+		k := it.Key()
+		v := it.Value()
+		_, offset := parseWalKey(k.Data())
+		value := append([]byte(nil), v.Data()...)
+		k.Free()
+		v.Free()
+
+		if err := fn(offset, value); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+// ScanJobWAL calls fn for each WAL entry of a job key, in timestamp order
+func (s *Store) ScanJobWAL(key []byte, fn func(partition int32, offset int64, timestamp time.Time, value []byte) error) error {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfWalIndex])
+	defer it.Close()
+
+	prefix := append(append([]byte(nil), key...), 0)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		k := it.Key()
+		suffix := k.Data()[len(prefix):]
+		ts := time.Unix(0, int64(binary.BigEndian.Uint64(suffix)))
+		partition, offset := parseWalKey(suffix[8:])
+		k.Free()
+
+		v, err := s.db.GetCF(ro, s.cfh[cfWal], walKey(partition, offset))
+		if err != nil {
+			return err
+		}
+		value := append([]byte(nil), v.Data()...)
+		v.Free()
+
+		if err := fn(partition, offset, ts, value); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+// partitionKey returns a key that is prefixed by a partition of the log
+func partitionKey(prefix []byte, partition int32, key []byte) []byte {
+	k := make([]byte, 0, len(prefix)+4+len(key))
+	k = append(k, prefix...)
+	k = append(k, walKey(partition, 0)[:4]...)
+
+	return append(k, key...)
+}
+
+// Checkpoint returns the offset of the last checkpoint of a partition, and
+// the jobs of the partition that were live at that point, keyed by
+// {workflow}:{name}. The offset is -1 if the partition has no checkpoint.
+func (s *Store) Checkpoint(partition int32) (int64, map[string][]byte, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	v, err := s.db.GetCF(ro, s.cfh[cfDefault], partitionKey(checkpointKey, partition, nil))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	offset := int64(-1)
+	if v.Size() == 8 {
+		offset = int64(binary.BigEndian.Uint64(v.Data()))
+	}
+	v.Free()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfSnapshot])
+	defer it.Close()
+
+	prefix := partitionKey(nil, partition, nil)
+	jobs := make(map[string][]byte)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		k := it.Key()
+		v := it.Value()
+		jobs[string(k.Data()[len(prefix):])] = append([]byte(nil), v.Data()...)
+		k.Free()
+		v.Free()
+	}
+
+	return offset, jobs, it.Err()
+}
+
+// SaveCheckpoint replaces the snapshot of the live jobs of a partition and
+// records the offset it was taken at, in a single write
+func (s *Store) SaveCheckpoint(partition int32, offset int64, jobs map[string][]byte) error {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+
+	prefix := partitionKey(nil, partition, nil)
+	it := s.db.NewIteratorCF(ro, s.cfh[cfSnapshot])
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		k := it.Key()
+		if _, ok := jobs[string(k.Data()[len(prefix):])]; !ok {
+			wb.DeleteCF(s.cfh[cfSnapshot], k.Data())
+		}
+		k.Free()
+	}
+	err := it.Err()
+	it.Close()
+	if err != nil {
+		return err
+	}
+
+	for k, v := range jobs {
+		wb.PutCF(s.cfh[cfSnapshot], partitionKey(nil, partition, []byte(k)), v)
+	}
+
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(offset))
+	wb.PutCF(s.cfh[cfDefault], partitionKey(checkpointKey, partition, nil), data)
+
+	return s.db.Write(s.walWriteOpt, wb)
+}
+
+// timerKey returns the key of the timer of a job, which orders the timers of
+// a partition by due time
+func timerKey(partition int32, due int64, key string) []byte {
+	k := walKey(partition, due)
+	return append(k, key...)
+}
+
+// PutTimer stores a delayed job of a partition, until it is due
+func (s *Store) PutTimer(partition int32, due int64, key string, value []byte) error {
+	return s.db.PutCF(s.walWriteOpt, s.cfh[cfTimer], timerKey(partition, due, key), value)
+}
+
+// DeleteTimer deletes the timer of a job
+func (s *Store) DeleteTimer(partition int32, due int64, key string) error {
+	return s.db.DeleteCF(s.walWriteOpt, s.cfh[cfTimer], timerKey(partition, due, key))
+}
+
+// ScanTimers calls fn for each timer of a partition that is due at or
+// before until, in due order
+func (s *Store) ScanTimers(partition int32, until int64, fn func(due int64, key string, value []byte) error) error {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfTimer])
+	defer it.Close()
+
+	prefix := partitionKey(nil, partition, nil)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		k := it.Key()
+		v := it.Value()
+		_, due := parseWalKey(k.Data())
+		key := string(k.Data()[12:])
+		value := append([]byte(nil), v.Data()...)
+		k.Free()
+		v.Free()
+
+		if due > until {
+			break
+		}
+
+		if err := fn(due, key, value); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+// PutWorkflow stores a workflow definition
+func (s *Store) PutWorkflow(name string, definition []byte) error {
+	return s.db.PutCF(s.walWriteOpt, s.cfh[cfWorkflow], []byte(name), definition)
+}
+
+// Workflow returns a workflow definition, or nil if it does not exist
+func (s *Store) Workflow(name string) ([]byte, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	v, err := s.db.GetCF(ro, s.cfh[cfWorkflow], []byte(name))
+	if err != nil {
+		return nil, err
+	}
+	defer v.Free()
+
+	if !v.Exists() {
+		return nil, nil
+	}
+
+	return append([]byte(nil), v.Data()...), nil
+}
+
+// Workflows returns all workflow definitions, keyed by workflow name
+func (s *Store) Workflows() (map[string][]byte, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfWorkflow])
+	defer it.Close()
+
+	defs := make(map[string][]byte)
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		k := it.Key()
+		v := it.Value()
+		defs[string(k.Data())] = append([]byte(nil), v.Data()...)
+		k.Free()
+		v.Free()
+	}
+
+	return defs, it.Err()
+}
+
+// deadLetterKey returns the key of a dead-lettered job, which groups the
+// jobs by workflow
+func deadLetterKey(workflow string, name string) []byte {
+	return []byte(workflow + "\x00" + name)
+}
+
+// PutDeadLetter stores a dead-lettered job
+func (s *Store) PutDeadLetter(workflow string, name string, value []byte) error {
+	return s.db.PutCF(s.walWriteOpt, s.cfh[cfDeadLetter], deadLetterKey(workflow, name), value)
+}
+
+// DeadLetter returns a dead-lettered job, or nil if it does not exist
+func (s *Store) DeadLetter(workflow string, name string) ([]byte, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	v, err := s.db.GetCF(ro, s.cfh[cfDeadLetter], deadLetterKey(workflow, name))
+	if err != nil {
+		return nil, err
+	}
+	defer v.Free()
+
+	if !v.Exists() {
+		return nil, nil
+	}
+
+	return append([]byte(nil), v.Data()...), nil
+}
+
+// DeadLetters returns the dead-lettered jobs of a workflow, in name order
+func (s *Store) DeadLetters(workflow string) ([][]byte, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfDeadLetter])
+	defer it.Close()
+
+	prefix := deadLetterKey(workflow, "")
+	var values [][]byte
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		v := it.Value()
+		values = append(values, append([]byte(nil), v.Data()...))
+		v.Free()
+	}
+
+	return values, it.Err()
+}
+
+// DeleteDeadLetter deletes a dead-lettered job. It only writes when the job
+// exists, since it is called for every job that becomes active.
+func (s *Store) DeleteDeadLetter(workflow string, name string) error {
+	v, err := s.DeadLetter(workflow, name)
+	if err != nil || v == nil {
+		return err
+	}
+
+	return s.db.DeleteCF(s.walWriteOpt, s.cfh[cfDeadLetter], deadLetterKey(workflow, name))
+}
+
+// PutHistory stores an event of a job. The events are keyed like the WAL
+// index, by job key, time and the log position of the job, so that the
+// events of a job are in time order.
+func (s *Store) PutHistory(key []byte, timestamp time.Time, partition int32, offset int64, value []byte) error {
+	return s.db.PutCF(s.walWriteOpt, s.cfh[cfHistory], walIndexKey(key, timestamp, partition, offset), value)
+}
+
+// ScanHistory iterates over the events of a job in time order
+func (s *Store) ScanHistory(key []byte, fn func(value []byte) error) error {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfHistory])
+	defer it.Close()
+
+	prefix := append(append([]byte(nil), key...), 0)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		v := it.Value()
+		value := append([]byte(nil), v.Data()...)
+		v.Free()
+
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+// PutJob stores the current record of a job, and replaces the index entries
+// of its previous record in the same write
+func (s *Store) PutJob(key []byte, value []byte, remove [][]byte, add [][]byte) error {
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+
+	wb.PutCF(s.cfh[cfJob], key, value)
+	for _, k := range remove {
+		wb.DeleteCF(s.cfh[cfJobIndex], k)
+	}
+	for _, k := range add {
+		wb.PutCF(s.cfh[cfJobIndex], k, nil)
+	}
+
+	return s.db.Write(s.walWriteOpt, wb)
+}
+
+// Job returns the current record of a job, or nil if it does not exist
+func (s *Store) Job(key []byte) ([]byte, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	v, err := s.db.GetCF(ro, s.cfh[cfJob], key)
+	if err != nil {
+		return nil, err
+	}
+	defer v.Free()
+
+	if !v.Exists() {
+		return nil, nil
+	}
+
+	return append([]byte(nil), v.Data()...), nil
+}
+
+// ScanJobIndex iterates over the index entries with a prefix in key order,
+// from the first entry at or after start. fn gets the part of the entry
+// after the prefix, and stops the scan by returning false.
+func (s *Store) ScanJobIndex(prefix []byte, start []byte, fn func(suffix []byte) (bool, error)) error {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+
+	it := s.db.NewIteratorCF(ro, s.cfh[cfJobIndex])
+	defer it.Close()
+
+	for it.Seek(start); it.ValidForPrefix(prefix); it.Next() {
+		k := it.Key()
+		suffix := append([]byte(nil), k.Data()[len(prefix):]...)
+		k.Free()
+
+		more, err := fn(suffix)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+	}
+
+	return it.Err()
 }
 
 // Close closes the storage engine
@@ -69,4 +630,12 @@ func (s *Store) Close() {
 	for _, i := range s.cfh {
 		i.Destroy()
 	}
+	s.cfh = nil
+
+	if s.db != nil {
+		s.db.Close()
+		s.db = nil
+	}
+
+	s.releaseLock()
 }
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStoreDataDir(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "conductor")
+	if err != nil {
+		t.Fatalf("failed to create data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewStore("cluster1", dir)
+	if err := store.Open(); err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+
+	// the data directory is locked while the store is open
+	if err := NewStore("cluster1", dir).Open(); err == nil {
+		t.Errorf("expected the data directory to be locked")
+	}
+
+	store.Close()
+
+	if err := NewStore("cluster2", dir).Open(); err == nil {
+		t.Errorf("expected the data directory to belong to cluster1")
+	}
+
+	store = NewStore("cluster1", dir)
+	if err := store.Open(); err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	store.Close()
+}
+
+func TestStoreWAL(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	entries := []struct {
+		partition int32
+		offset    int64
+		key       string
+		value     string
+	}{
+		{1, 5, "wf1:a", "a1"},
+		{0, 7, "wf1:b", "b1"},
+		{1, 6, "wf1:a", "a2"},
+		// same key and timestamp as the previous entry
+		{1, 7, "wf1:a", "a3"},
+	}
+	for _, e := range entries {
+		if err := store.AppendWAL(e.partition, e.offset, []byte(e.key), now, []byte(e.value), nil); err != nil {
+			t.Fatalf("failed to append WAL entry: %v", err)
+		}
+	}
+
+	var values []string
+	err := store.ScanWAL(1, 5, func(offset int64, value []byte) error {
+		values = append(values, string(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan WAL: %v", err)
+	}
+	if len(values) != 2 || values[0] != "a2" || values[1] != "a3" {
+		t.Errorf("expected [a2 a3] after [1]@5, actual: %v", values)
+	}
+
+	values = nil
+	err = store.ScanJobWAL([]byte("wf1:a"), func(partition int32, offset int64, ts time.Time, value []byte) error {
+		values = append(values, string(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan job WAL: %v", err)
+	}
+	if len(values) != 3 || values[0] != "a1" || values[1] != "a2" || values[2] != "a3" {
+		t.Errorf("expected [a1 a2 a3] for wf1:a, actual: %v", values)
+	}
+
+	offsets, err := store.Offsets()
+	if err != nil {
+		t.Fatalf("failed to read offsets: %v", err)
+	}
+	if offsets[0] != 7 || offsets[1] != 7 {
+		t.Errorf("expected offsets 7 for partitions 0 and 1, actual: %v", offsets)
+	}
+}
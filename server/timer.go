@@ -0,0 +1,143 @@
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// timerInterval is how often the delayed jobs are checked for being due
+const timerInterval = time.Second
+
+// Timers holds the delayed jobs of the owned partitions out of the queues,
+// until their not_before time is due. The timers are persisted in the store,
+// ordered by partition and due time, so that they survive restarts.
+type Timers struct {
+	sync.Mutex
+	store *Store
+	// timers is the timer of each delayed job, by {workflow}:{name}
+	timers map[string]timer
+}
+
+// timer is the partition and the due time of a delayed job
+type timer struct {
+	partition int32
+	due       int64
+}
+
+// NewTimers creates the timers of delayed jobs
+func NewTimers(store *Store) *Timers {
+	return &Timers{
+		store:  store,
+		timers: make(map[string]timer),
+	}
+}
+
+// Delayed tells whether a job is not due yet
+func Delayed(job Job, now time.Time) bool {
+	return job.NotBefore > now.UnixNano()
+}
+
+// Load reads the timers of a partition from the store. If a job has two
+// timers, because a crash happened while it was rescheduled, the earlier
+// one is kept.
+func (t *Timers) Load(partition int32) error {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.store.ScanTimers(partition, math.MaxInt64, func(due int64, key string, value []byte) error {
+		if _, ok := t.timers[key]; !ok {
+			t.timers[key] = timer{partition: partition, due: due}
+		}
+		return nil
+	})
+}
+
+// Drop forgets the timers of a partition that is no longer owned. They stay
+// in the store, and are loaded again when the partition is assigned again.
+func (t *Timers) Drop(partition int32) {
+	t.Lock()
+	defer t.Unlock()
+
+	for k, tm := range t.timers {
+		if tm.partition == partition {
+			delete(t.timers, k)
+		}
+	}
+}
+
+// Schedule holds a job until its not_before time, replacing the previous
+// timer of the job
+func (t *Timers) Schedule(job Job) error {
+	data, err := proto.Marshal(&job)
+	if err != nil {
+		return err
+	}
+
+	key := jobKey(job.Workflow, job.Name)
+	next := timer{partition: job.Partition, due: job.NotBefore}
+
+	t.Lock()
+	defer t.Unlock()
+
+	// the new timer is written first, so that a crash never loses the job
+	if err := t.store.PutTimer(next.partition, next.due, key, data); err != nil {
+		return err
+	}
+
+	if prev, ok := t.timers[key]; ok && prev != next {
+		if err := t.store.DeleteTimer(prev.partition, prev.due, key); err != nil {
+			return err
+		}
+	}
+	t.timers[key] = next
+
+	return nil
+}
+
+// Cancel deletes the timer of a job, if it is delayed
+func (t *Timers) Cancel(workflow string, name string) error {
+	key := jobKey(workflow, name)
+
+	t.Lock()
+	defer t.Unlock()
+
+	tm, ok := t.timers[key]
+	if !ok {
+		return nil
+	}
+
+	if err := t.store.DeleteTimer(tm.partition, tm.due, key); err != nil {
+		return err
+	}
+	delete(t.timers, key)
+
+	return nil
+}
+
+// Due returns the jobs of a partition that are due at now, in due order. The
+// timers are kept until they are cancelled, once the jobs are safely in
+// their queues.
+func (t *Timers) Due(partition int32, now time.Time) ([]Job, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	var jobs []Job
+	err := t.store.ScanTimers(partition, now.UnixNano(), func(due int64, key string, value []byte) error {
+		// a timer that was replaced before the old one was deleted
+		if tm, ok := t.timers[key]; !ok || tm.due != due {
+			return t.store.DeleteTimer(partition, due, key)
+		}
+
+		j := Job{}
+		if err := proto.Unmarshal(value, &j); err != nil {
+			return err
+		}
+		jobs = append(jobs, j)
+		return nil
+	})
+
+	return jobs, err
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimers(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+
+	now := time.Now()
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1", NotBefore: now.Add(time.Hour).UnixNano()})
+	appendTestJob(t, store, m, 1, Job{Workflow: "wf1", Name: "b", State: "s1", NotBefore: now.Add(2 * time.Hour).UnixNano()})
+
+	// b is rescheduled to run first
+	appendTestJob(t, store, m, 2, Job{Workflow: "wf1", Name: "b", State: "s1", NotBefore: now.Add(time.Minute).UnixNano()})
+
+	if j := m.tryPoll("wf1", "s1"); j != nil {
+		t.Fatalf("expected no job before it is due, actual: %v", j)
+	}
+
+	// the timers survive a restart
+	m.Stop()
+	m = NewMemStore(store, 100, time.Minute, nil)
+	assignTestPartition(t, m)
+
+	m.fire(now.Add(30 * time.Minute))
+	if j := m.tryPoll("wf1", "s1"); j == nil || j.Name != "b" {
+		t.Errorf("expected job b to be due, actual: %v", j)
+	}
+	if j := m.tryPoll("wf1", "s1"); j != nil {
+		t.Errorf("expected job a not to be due, actual: %v", j)
+	}
+
+	m.fire(now.Add(3 * time.Hour))
+	if j := m.tryPoll("wf1", "s1"); j == nil || j.Name != "a" {
+		t.Errorf("expected job a to be due, actual: %v", j)
+	}
+	if j := m.tryPoll("wf1", "s1"); j != nil {
+		t.Errorf("expected the replaced timer of b not to fire, actual: %v", j)
+	}
+}
+
+func TestTimersFull(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	m := NewMemStore(store, 1, time.Minute, nil)
+	assignTestPartition(t, m)
+
+	now := time.Now()
+	appendTestJob(t, store, m, 0, Job{Workflow: "wf1", Name: "a", State: "s1"})
+	appendTestJob(t, store, m, 1, Job{Workflow: "wf1", Name: "b", State: "s1", NotBefore: now.Add(time.Minute).UnixNano()})
+
+	// a due job waits in its timer while its queue is full
+	m.fire(now.Add(time.Hour))
+	if n := m.queue(0, "wf1", "s1").Size(); n != 1 {
+		t.Errorf("expected the queue to stay at capacity, actual: %d", n)
+	}
+
+	if j := m.tryPoll("wf1", "s1"); j == nil || j.Name != "a" {
+		t.Fatalf("expected job a, actual: %v", j)
+	}
+	m.fire(now.Add(time.Hour))
+	if j := m.tryPoll("wf1", "s1"); j == nil || j.Name != "b" {
+		t.Errorf("expected job b to be due, actual: %v", j)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// LogMessage is a message in a partition of the log
+type LogMessage struct {
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Timestamp time.Time
+}
+
+// PartitionsAssigned is delivered when partitions of the log are assigned
+// to this member of the consumer group
+type PartitionsAssigned struct {
+	Partitions []int32
+}
+
+// PartitionsRevoked is delivered when partitions of the log are taken away
+// from this member of the consumer group
+type PartitionsRevoked struct {
+	Partitions []int32
+}
+
+// LogEvent is an event delivered by a subscription to the log. It is one
+// of *LogMessage, PartitionsAssigned, PartitionsRevoked, *Member,
+// *WorkflowDefinition or error.
+type LogEvent interface{}
+
+// LogTransport is the replicated log that jobs are published to, and that
+// the WAL is consumed from. The log is partitioned by message key, and the
+// partitions are balanced between the members of a consumer group.
+type LogTransport interface {
+	// Produce publishes a message, and blocks until the log accepts it.
+	// It returns the message with its partition and offset.
+	Produce(key []byte, value []byte) (*LogMessage, error)
+
+	// Partition returns the partition of a message key.
+	Partition(key []byte) (int32, error)
+
+	// Announce publishes the partitions owned by this member. The
+	// announcements of all members, including the earlier ones, are
+	// delivered to every subscription.
+	Announce(member *Member) error
+
+	// PublishWorkflow publishes a workflow definition. The definitions of
+	// all members, including the earlier ones, are delivered to every
+	// subscription in the order they are published.
+	PublishWorkflow(def *WorkflowDefinition) error
+
+	// Subscribe joins the consumer group. Messages and rebalance events
+	// are delivered on the returned channel.
+	Subscribe() (<-chan LogEvent, error)
+
+	// Assign starts consuming partitions after they are assigned. Each
+	// partition is consumed from the message after the given offset, or
+	// from the first message when the offset is -1.
+	Assign(offsets map[int32]int64) error
+
+	// HighWatermark returns the offset of the next message to be produced
+	// to a partition.
+	HighWatermark(partition int32) (int64, error)
+
+	// Unassign stops consuming all partitions.
+	Unassign() error
+
+	// Commit records that the messages of a partition up to and including
+	// offset are processed.
+	Commit(partition int32, offset int64) error
+
+	// Close leaves the consumer group and releases the transport.
+	Close() error
+}
+
+// partitionOf returns the partition of a message key, in a log with a
+// number of partitions
+func partitionOf(key []byte, partitions int) int32 {
+	h := fnv.New32a()
+	h.Write(key)
+
+	return int32(h.Sum32() % uint32(partitions))
+}
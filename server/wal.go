@@ -3,93 +3,108 @@ package server
 import (
 	"fmt"
 	"os"
-	"os/signal"
+	"sort"
 	"sync"
-	"syscall"
 
-	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
 )
 
-// Wal is the Write-Ahead-Log in RocksDB, persisted from Kafka
+// Wal is the Write-Ahead-Log in RocksDB, persisted from the log transport
 type Wal struct {
-	name       string
-	brokers    string
+	transport  LogTransport
 	store      *Store
-	consumer   *kafka.Consumer
-	sigchan    chan os.Signal
+	memstore   *MemStore
+	membership *Membership
+	// offsets is the offset of the last message appended to the WAL, for
+	// each partition
+	offsets map[int32]int64
+	// assigned is the set of partitions that are consumed
+	assigned map[int32]bool
+	// catchup is the offset of the last message in the log when a
+	// partition was assigned, for the partitions whose consumer has not
+	// reached it yet
+	catchup map[int32]int64
+	// verbose prints every message of the log
+	verbose bool
+	// errC receives the error that stops the WAL service
+	errC       chan error
+	stopC      chan bool
 	shutdownWG sync.WaitGroup
 }
 
-// NewWal creates a new WAL instance
-func NewWal(name string, brokers string, store *Store) *Wal {
+// NewWal creates a new WAL instance. The partitions owned by this member
+// are announced to the others, and their announcements are applied to the
+// membership view.
+func NewWal(transport LogTransport, store *Store, memstore *MemStore, membership *Membership) *Wal {
 	return &Wal{
-		name:    name,
-		brokers: brokers,
-		store:   store,
-		sigchan: make(chan os.Signal, 1),
+		transport:  transport,
+		store:      store,
+		memstore:   memstore,
+		membership: membership,
+		errC:       make(chan error, 1),
+		stopC:      make(chan bool),
 	}
 }
 
 // Start starts the WAL service.
-func (w *Wal) Start() {
-	signal.Notify(w.sigchan, syscall.SIGINT, syscall.SIGTERM)
-
-	fmt.Printf("creating Kafka consumer for %s, broker: %s", w.name, w.brokers)
-
-	c, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers":               w.brokers,
-		"group.id":                        w.name,
-		"session.timeout.ms":              6000,
-		"go.events.channel.enable":        true,
-		"go.application.rebalance.enable": true,
-		"default.topic.config":            kafka.ConfigMap{"auto.offset.reset": "earliest"},
-	})
-
+func (w *Wal) Start() error {
+	offsets, err := w.store.Offsets()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create consume: %s\n", err)
-		os.Exit(1)
+		return err
 	}
+	w.offsets = offsets
+	w.assigned = make(map[int32]bool)
+	w.catchup = make(map[int32]int64)
 
-	w.consumer = c
-
-	fmt.Printf("Created Consumer %v\n", c)
-
-	err = c.SubscribeTopics([]string{w.name}, nil)
+	events, err := w.transport.Subscribe()
+	if err != nil {
+		return err
+	}
 
 	w.shutdownWG.Add(1)
-	go w.runWal()
+	go w.runWal(events)
+
+	return nil
 }
 
-func (w *Wal) runWal() {
+func (w *Wal) runWal(events <-chan LogEvent) {
 
 	run := true
 
 	for run == true {
 		select {
-		case sig := <-w.sigchan:
-			fmt.Printf("Caught signal %v: terminating\n", sig)
+		case <-w.stopC:
 			run = false
 
-		case ev := <-w.consumer.Events():
+		case ev := <-events:
 			switch e := ev.(type) {
-			case kafka.AssignedPartitions:
-				fmt.Fprintf(os.Stderr, "%% %v\n", e)
-				w.consumer.Assign(e.Partitions)
+			case PartitionsAssigned:
+				fmt.Fprintf(os.Stderr, "%% Assigned partitions %v\n", e.Partitions)
+				if err := w.assign(e.Partitions); err != nil {
+					w.fail(fmt.Errorf("failed to assign partitions %v: %v", e.Partitions, err))
+					run = false
+				}
+
+			case PartitionsRevoked:
+				fmt.Fprintf(os.Stderr, "%% Revoked partitions %v\n", e.Partitions)
+				w.revoke(e.Partitions)
 
-			case kafka.RevokedPartitions:
-				fmt.Fprintf(os.Stderr, "%% %v\n", e)
-				w.consumer.Unassign()
+			case *Member:
+				w.membership.Update(e)
 
-			case *kafka.Message:
-				fmt.Printf("%% Message on %s:\n%s\n",
-					e.TopicPartition, string(e.Value))
+			case *WorkflowDefinition:
+				if _, err := w.memstore.workflows.Put([]byte(e.Definition)); err != nil {
+					fmt.Fprintf(os.Stderr, "%% Failed to put workflow %s: %v\n", e.Name, err)
+				}
 
-				// store the data in RocksDB, ordered by Kafka message timestamp
-				key := fmt.Sprintf("%s:%d", e.Key, e.Timestamp.UnixNano())
-				w.store.AppendWAL([]byte(key), e.Value)
+			case *LogMessage:
+				if err := w.append(e); err != nil {
+					w.fail(fmt.Errorf("failed to append message [%d]@%d to WAL: %v", e.Partition, e.Offset, err))
+					run = false
+				}
 
-			case kafka.Error:
-				fmt.Fprintf(os.Stderr, "%% Error: %v\n", e)
+			case error:
+				w.fail(e)
 				run = false
 			}
 		}
@@ -98,14 +113,176 @@ func (w *Wal) runWal() {
 	w.shutdownWG.Done()
 }
 
-// Stop stops the WAL service
+// fail reports the error that stops the WAL service, since the messages of
+// the log are no longer applied
+func (w *Wal) fail(err error) {
+	fmt.Fprintf(os.Stderr, "%% Error: %v\n", err)
+
+	select {
+	case w.errC <- err:
+	default:
+	}
+}
+
+// Err returns a channel that receives the error that stops the WAL
+// service
+func (w *Wal) Err() <-chan error {
+	return w.errC
+}
+
+// assign rebuilds the jobs of the assigned partitions from the WAL, and
+// starts consuming them after the last message in the WAL, so that the WAL
+// holds every message of a partition even when it was consumed by another
+// node in the meantime. The jobs of a partition are served once the
+// consumer reaches the end of the log as of the assignment, since the
+// messages of the previous owner are not in the WAL before then.
+func (w *Wal) assign(partitions []int32) error {
+	if err := w.memstore.Assign(partitions); err != nil {
+		return err
+	}
+
+	offsets := make(map[int32]int64, len(partitions))
+	for _, p := range partitions {
+		offset, ok := w.offsets[p]
+		if !ok {
+			offset = -1
+		}
+		offsets[p] = offset
+		w.assigned[p] = true
+
+		high, err := w.transport.HighWatermark(p)
+		if err != nil {
+			return fmt.Errorf("failed to query the end of partition %d: %v", p, err)
+		}
+		if offset >= high-1 {
+			w.memstore.CaughtUp(p)
+		} else {
+			w.catchup[p] = high - 1
+		}
+	}
+
+	if err := w.transport.Assign(offsets); err != nil {
+		return err
+	}
+
+	return w.announce()
+}
+
+// revoke stops consuming the revoked partitions, and drops their jobs
+func (w *Wal) revoke(partitions []int32) {
+	for _, p := range partitions {
+		delete(w.assigned, p)
+		delete(w.catchup, p)
+	}
+
+	if err := w.transport.Unassign(); err != nil {
+		fmt.Fprintf(os.Stderr, "%% Failed to unassign partitions: %v\n", err)
+	}
+	w.memstore.Revoke(partitions)
+
+	if err := w.announce(); err != nil {
+		fmt.Fprintf(os.Stderr, "%% Failed to announce partitions: %v\n", err)
+	}
+}
+
+// announce publishes the partitions that this member owns
+func (w *Wal) announce() error {
+	partitions := make([]int32, 0, len(w.assigned))
+	for p := range w.assigned {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	return w.transport.Announce(&Member{
+		Address:    w.membership.address,
+		Partitions: partitions,
+	})
+}
+
+// append writes a message to the WAL and applies it to the memstore.
+// The offset is committed to the log only after the WAL entry is written,
+// and messages that are already in the WAL are skipped, so that every
+// message is appended once even when the log delivers it again.
+func (w *Wal) append(e *LogMessage) error {
+	// a message that was in flight when its partition was revoked
+	if !w.assigned[e.Partition] {
+		return nil
+	}
+
+	if last, ok := w.offsets[e.Partition]; ok && e.Offset <= last {
+		return nil
+	}
+
+	if w.verbose {
+		fmt.Printf("%% Message on [%d]@%d:\n%s\n",
+			e.Partition, e.Offset, string(e.Value))
+	}
+
+	job := Job{}
+	decodeErr := proto.Unmarshal(e.Value, &job)
+	job.Partition = e.Partition
+	job.Offset = e.Offset
+
+	// the event of the job is recorded in its history with the message
+	var event []byte
+	if decodeErr == nil {
+		var err error
+		if event, err = proto.Marshal(logEvent(job, e.Timestamp)); err != nil {
+			fmt.Fprintf(os.Stderr, "%% Failed to encode history of job %s: %v\n", e.Key, err)
+		}
+	}
+
+	// store the data in RocksDB, keyed by its position in the log
+	if err := w.store.AppendWAL(e.Partition, e.Offset, e.Key, e.Timestamp, e.Value, event); err != nil {
+		return err
+	}
+	w.offsets[e.Partition] = e.Offset
+
+	if err := decodeErr; err != nil {
+		fmt.Fprintf(os.Stderr, "%% Failed to decode job %s: %v\n", e.Key, err)
+
+		// the message is kept in the dead-letter queue of the workflow
+		// of its key, since the job cannot be read
+		workflow, name := parseJobKey(string(e.Key))
+		err = w.memstore.deadLetters.Put(&DeadLetter{
+			Workflow:  workflow,
+			Name:      name,
+			Error:     fmt.Sprintf("invalid job: %v", err),
+			Payload:   e.Value,
+			FailedAt:  e.Timestamp.UnixNano(),
+			Partition: e.Partition,
+			Offset:    e.Offset,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%% Failed to dead-letter message [%d]@%d: %v\n", e.Partition, e.Offset, err)
+		}
+	} else {
+		w.memstore.Apply(job, e.Timestamp)
+	}
+
+	if last, ok := w.catchup[e.Partition]; ok && e.Offset >= last {
+		delete(w.catchup, e.Partition)
+		w.memstore.CaughtUp(e.Partition)
+		fmt.Fprintf(os.Stderr, "%% Caught up with partition %d at offset %d\n", e.Partition, e.Offset)
+	}
+
+	if err := w.transport.Commit(e.Partition, e.Offset); err != nil {
+		fmt.Fprintf(os.Stderr, "%% Failed to commit offset [%d]@%d: %v\n", e.Partition, e.Offset, err)
+	}
+
+	return nil
+}
+
+// Stop stops the WAL service. The member announces that it owns no
+// partition, since the revoke of its partitions is not consumed any more.
 func (w *Wal) Stop() {
 	fmt.Println("stopping WAL service")
-	w.sigchan <- syscall.SIGTERM
+	close(w.stopC)
 	w.shutdownWG.Wait()
 
-	if w.consumer != nil {
-		w.consumer.Close()
+	w.assigned = make(map[int32]bool)
+	if err := w.announce(); err != nil {
+		fmt.Fprintf(os.Stderr, "%% Failed to announce partitions: %v\n", err)
 	}
 
 	fmt.Println("WAL service stopped.")
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestWalCatchUp(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	// messages of a previous owner that are not in the local WAL
+	log := NewMemoryLog(1)
+	transport := log.Transport()
+	defer transport.Close()
+
+	var messages []*LogMessage
+	for _, name := range []string{"a", "b"} {
+		data, err := proto.Marshal(&Job{Workflow: "wf1", Name: name, State: "s1"})
+		if err != nil {
+			t.Fatalf("failed to encode job: %v", err)
+		}
+
+		msg, err := transport.Produce([]byte(jobKey("wf1", name)), data)
+		if err != nil {
+			t.Fatalf("failed to produce job: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	m := NewMemStore(store, 100, time.Minute, nil)
+	w := NewWal(transport, store, m, NewMembership("a:1"))
+	w.offsets = make(map[int32]int64)
+	w.assigned = make(map[int32]bool)
+	w.catchup = make(map[int32]int64)
+
+	if err := w.assign([]int32{0}); err != nil {
+		t.Fatalf("failed to assign partition: %v", err)
+	}
+	if m.Ready(0) {
+		t.Fatalf("expected partition 0 not to be served before it caught up")
+	}
+
+	if err := w.append(messages[0]); err != nil {
+		t.Fatalf("failed to append message: %v", err)
+	}
+	if m.Ready(0) || m.tryPoll("wf1", "s1") != nil {
+		t.Errorf("expected partition 0 not to be served before the last message")
+	}
+
+	if err := w.append(messages[1]); err != nil {
+		t.Fatalf("failed to append message: %v", err)
+	}
+	if !m.Ready(0) {
+		t.Fatalf("expected partition 0 to be served once it caught up")
+	}
+	if j := m.tryPoll("wf1", "s1"); j == nil || j.Name != "a" {
+		t.Errorf("expected to poll job a, actual: %v", j)
+	}
+
+	// a partition whose messages are all in the WAL is served right away
+	w.revoke([]int32{0})
+	if err := w.assign([]int32{0}); err != nil {
+		t.Fatalf("failed to assign partition: %v", err)
+	}
+	if !m.Ready(0) {
+		t.Errorf("expected partition 0 to be served after its WAL was replayed")
+	}
+}
+
+// failingTransport delivers the events of a test to the WAL
+type failingTransport struct {
+	LogTransport
+	events chan LogEvent
+}
+
+func (t *failingTransport) Subscribe() (<-chan LogEvent, error) {
+	return t.events, nil
+}
+
+func (t *failingTransport) Announce(member *Member) error {
+	return nil
+}
+
+func TestWalFail(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close()
+
+	transport := &failingTransport{events: make(chan LogEvent)}
+	w := NewWal(transport, store, NewMemStore(store, 100, time.Minute, nil), NewMembership("a:1"))
+	if err := w.Start(); err != nil {
+		t.Fatalf("failed to start WAL: %v", err)
+	}
+	defer w.Stop()
+
+	// an error of the log stops the WAL, and is reported
+	transport.events <- errors.New("broker down")
+	select {
+	case err := <-w.Err():
+		if err.Error() != "broker down" {
+			t.Errorf("expected the error of the log, actual: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the WAL to report its failure")
+	}
+}
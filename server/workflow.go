@@ -0,0 +1,221 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Workflow is a workflow definition. It declares the states of a job, the
+// transitions allowed between them and per-state settings. A definition is
+// written in YAML or JSON, for example:
+//
+//	name: etl
+//	states:
+//	  - name: extract
+//	    transitions: [transform]
+//	  - name: transform
+//	    transitions: [load]
+//	    lease_timeout: 5m
+//	    retry:
+//	      max_attempts: 3
+//	      initial_backoff: 1m
+//	      failure_state: review
+//	  - name: load
+//	    terminal: true
+//	  - name: review
+//	    terminal: true
+type Workflow struct {
+	Name   string          `yaml:"name"`
+	States []WorkflowState `yaml:"states"`
+}
+
+// WorkflowState is a state of a workflow
+type WorkflowState struct {
+	Name string `yaml:"name"`
+	// Transitions are the states a job may move to from this state
+	Transitions []string `yaml:"transitions"`
+	// Terminal allows a job to finish in this state
+	Terminal bool `yaml:"terminal"`
+	// LeaseTimeout overrides the visibility timeout of the jobs polled
+	// from this state
+	LeaseTimeout time.Duration `yaml:"lease_timeout"`
+	// Retry retries the jobs that fail in this state. Without a policy a
+	// failed job is done.
+	Retry *RetryPolicy `yaml:"retry"`
+}
+
+// ParseWorkflow parses and validates a YAML or JSON workflow definition
+func ParseWorkflow(data []byte) (*Workflow, error) {
+	w := Workflow{}
+	if err := yaml.UnmarshalStrict(data, &w); err != nil {
+		return nil, err
+	}
+
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// Validate checks that the states and transitions are well formed
+func (w *Workflow) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("missing workflow name")
+	}
+
+	if len(w.States) == 0 {
+		return fmt.Errorf("workflow %s has no state", w.Name)
+	}
+
+	names := make(map[string]bool)
+	for _, s := range w.States {
+		if s.Name == "" {
+			return fmt.Errorf("workflow %s has a state without name", w.Name)
+		}
+
+		if names[s.Name] {
+			return fmt.Errorf("workflow %s has duplicated state %s", w.Name, s.Name)
+		}
+		names[s.Name] = true
+
+		if s.LeaseTimeout < 0 {
+			return fmt.Errorf("state %s has a negative lease timeout", s.Name)
+		}
+	}
+
+	for _, s := range w.States {
+		for _, t := range s.Transitions {
+			if !names[t] {
+				return fmt.Errorf("state %s has a transition to unknown state %s", s.Name, t)
+			}
+		}
+
+		if s.Retry == nil {
+			continue
+		}
+
+		if err := s.Retry.Validate(); err != nil {
+			return fmt.Errorf("state %s has an invalid retry policy: %v", s.Name, err)
+		}
+
+		if f := s.Retry.FailureState; f != "" && (!names[f] || f == s.Name) {
+			return fmt.Errorf("state %s has an invalid failure state %s", s.Name, f)
+		}
+	}
+
+	return nil
+}
+
+// State returns the definition of a state, or nil if it is unknown
+func (w *Workflow) State(name string) *WorkflowState {
+	for i := range w.States {
+		if w.States[i].Name == name {
+			return &w.States[i]
+		}
+	}
+
+	return nil
+}
+
+// CanTransition tells whether a job may move from one state to another
+func (w *Workflow) CanTransition(from string, to string) bool {
+	s := w.State(from)
+	if s == nil {
+		return false
+	}
+
+	for _, t := range s.Transitions {
+		if t == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Workflows keeps the workflow definitions in memory, and persists them
+// in the Store
+type Workflows struct {
+	sync.RWMutex
+	store     *Store
+	workflows map[string]*Workflow
+}
+
+// NewWorkflows creates a new instance of Workflows
+func NewWorkflows(store *Store) *Workflows {
+	return &Workflows{
+		store:     store,
+		workflows: make(map[string]*Workflow),
+	}
+}
+
+// Load reads the workflow definitions from the store
+func (w *Workflows) Load() error {
+	defs, err := w.store.Workflows()
+	if err != nil {
+		return err
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	for name, data := range defs {
+		wf, err := ParseWorkflow(data)
+		if err != nil {
+			return fmt.Errorf("invalid definition of workflow %s: %v", name, err)
+		}
+		w.workflows[name] = wf
+	}
+
+	return nil
+}
+
+// Put validates and stores a workflow definition, replacing the previous
+// definition of the same workflow
+func (w *Workflows) Put(data []byte) (*Workflow, error) {
+	wf, err := ParseWorkflow(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.store.PutWorkflow(wf.Name, data); err != nil {
+		return nil, err
+	}
+
+	w.Lock()
+	w.workflows[wf.Name] = wf
+	w.Unlock()
+
+	return wf, nil
+}
+
+// Get returns a workflow definition, or nil if it is not defined
+func (w *Workflows) Get(name string) *Workflow {
+	if w == nil {
+		return nil
+	}
+
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.workflows[name]
+}
+
+// LeaseTimeout returns the visibility timeout of a workflow state, or
+// zero if the state does not override it
+func (w *Workflows) LeaseTimeout(workflow string, state string) time.Duration {
+	wf := w.Get(workflow)
+	if wf == nil {
+		return 0
+	}
+
+	if s := wf.State(state); s != nil {
+		return s.LeaseTimeout
+	}
+
+	return 0
+}
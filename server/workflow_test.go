@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+const testWorkflow = `
+name: etl
+states:
+  - name: extract
+    transitions: [transform]
+  - name: transform
+    transitions: [load]
+    lease_timeout: 5m
+  - name: load
+    terminal: true
+`
+
+func TestParseWorkflow(t *testing.T) {
+	t.Parallel()
+
+	wf, err := ParseWorkflow([]byte(testWorkflow))
+	if err != nil {
+		t.Fatalf("failed to parse workflow: %v", err)
+	}
+
+	if !wf.CanTransition("extract", "transform") {
+		t.Errorf("expected transition from extract to transform")
+	}
+
+	if wf.CanTransition("extract", "load") {
+		t.Errorf("unexpected transition from extract to load")
+	}
+
+	if s := wf.State("transform"); s == nil || s.LeaseTimeout != 5*time.Minute {
+		t.Errorf("expected lease timeout of transform: 5m, actual: %v", s)
+	}
+
+	if s := wf.State("load"); s == nil || !s.Terminal {
+		t.Errorf("expected load to be terminal")
+	}
+}
+
+func TestParseWorkflowJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseWorkflow([]byte(`{"name": "wf1", "states": [{"name": "a", "terminal": true}]}`))
+	if err != nil {
+		t.Errorf("failed to parse workflow: %v", err)
+	}
+}
+
+func TestParseWorkflowInvalid(t *testing.T) {
+	t.Parallel()
+
+	defs := []string{
+		`states: [{name: a}]`,
+		`name: wf1`,
+		`{name: wf1, states: [{name: a}, {name: a}]}`,
+		`{name: wf1, states: [{name: a, transitions: [b]}]}`,
+		`{name: wf1, states: [{name: a, unknown: true}]}`,
+		`{name: wf1, states: [{name: a, retry: {failure_state: b}}]}`,
+		`{name: wf1, states: [{name: a, retry: {jitter: 2}}]}`,
+	}
+
+	for _, d := range defs {
+		if _, err := ParseWorkflow([]byte(d)); err == nil {
+			t.Errorf("expected error for definition %s", d)
+		}
+	}
+}